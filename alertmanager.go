@@ -0,0 +1,320 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertmanagerWebhook is the payload Prometheus Alertmanager posts to webhook
+// receivers.
+type AlertmanagerWebhook struct {
+	Version           string            `json:"version"`
+	GroupKey          string            `json:"groupKey"`
+	Status            string            `json:"status"`
+	Receiver          string            `json:"receiver"`
+	GroupLabels       map[string]string `json:"groupLabels"`
+	CommonLabels      map[string]string `json:"commonLabels"`
+	CommonAnnotations map[string]string `json:"commonAnnotations"`
+	ExternalURL       string            `json:"externalURL"`
+	Alerts            []Alert           `json:"alerts"`
+}
+
+// An Alert is a single alert of an AlertmanagerWebhook.
+type Alert struct {
+	Status       string            `json:"status"`
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+	Fingerprint  string            `json:"fingerprint"`
+}
+
+// key identifies the alert across notifications.
+func (a *Alert) key() string {
+	if a.Fingerprint != "" {
+		return a.Fingerprint
+	}
+	names := make([]string, 0, len(a.Labels))
+	for name := range a.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + "=" + a.Labels[name] + "\x00")
+	}
+	return b.String()
+}
+
+// An AlertRoute sends alerts whose labels have all the values in Match to
+// Targets.
+type AlertRoute struct {
+	Match   map[string]string
+	Targets []Target
+}
+
+func (r *AlertRoute) matches(a *Alert) bool {
+	for name, value := range r.Match {
+		if a.Labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// AlertmanagerHandler is an http.Handler receiving Alertmanager webhooks. Each
+// firing alert is pushed as a link to its generator URL, or as a note if it
+// has none. Alertmanager repeats alerts that keep firing; these are pushed
+// only once per target. When an alert resolves, the pushes made for it are
+// dismissed. Alerts that neither repeat nor resolve within Expiry, for
+// example because they were silenced, are forgotten.
+type AlertmanagerHandler struct {
+	Client *Client
+
+	// Routes are tried in order; the first one matching an alert's labels
+	// decides its targets. Alerts matching no route go to Default, or to
+	// all of the user's devices if Default is empty.
+	Routes  []AlertRoute
+	Default []Target
+
+	// NotifyResolved additionally pushes a note when an alert resolves.
+	NotifyResolved bool
+
+	// Expiry is how long an alert is remembered after it was last received.
+	// It should exceed Alertmanager's repeat_interval, or alerts that keep
+	// firing are pushed again. Defaults to 24 hours.
+	Expiry time.Duration
+
+	mu     sync.Mutex
+	pushed map[string]*alertState // by alert key
+}
+
+// alertState is what the handler remembers about a firing alert.
+type alertState struct {
+	pushes []alertPush
+	seen   time.Time
+}
+
+// alertPush is a push made to a target for a firing alert. Iden is empty
+// while the push is in flight.
+type alertPush struct {
+	target Target
+	iden   string
+}
+
+// NewAlertmanagerHandler creates an AlertmanagerHandler pushing with the
+// client.
+func NewAlertmanagerHandler(c *Client) *AlertmanagerHandler {
+	return &AlertmanagerHandler{Client: c}
+}
+
+func (h *AlertmanagerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var msg AlertmanagerWebhook
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&msg); err != nil {
+		http.Error(w, "Invalid webhook payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Notify(&msg); err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Notify pushes the alerts of msg. It returns the first error encountered but
+// handles all alerts regardless.
+func (h *AlertmanagerHandler) Notify(msg *AlertmanagerWebhook) error {
+	var firstErr error
+	for i := range msg.Alerts {
+		a := &msg.Alerts[i]
+		var err error
+		if a.Status == "resolved" {
+			err = h.resolve(a)
+		} else {
+			err = h.fire(a)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (h *AlertmanagerHandler) targets(a *Alert) []Target {
+	for i := range h.Routes {
+		if h.Routes[i].matches(a) {
+			return h.Routes[i].Targets
+		}
+	}
+	if len(h.Default) > 0 {
+		return h.Default
+	}
+	return []Target{{}}
+}
+
+func (h *AlertmanagerHandler) fire(a *Alert) error {
+	title, body := alertText(a)
+	var firstErr error
+	for _, t := range h.claim(a.key(), h.targets(a)) {
+		var data interface{} = t.note(title, body)
+		if a.GeneratorURL != "" {
+			data = t.link(title, a.GeneratorURL, body)
+		}
+		pushed, err := h.Client.Push("/pushes", data)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		var iden string
+		if err == nil {
+			iden = pushed.Iden
+		}
+		h.record(a.key(), t, iden, err == nil)
+	}
+	return firstErr
+}
+
+// claim returns the targets that have not been pushed the alert with the
+// given key yet, and marks them as being pushed.
+func (h *AlertmanagerHandler) claim(key string, targets []Target) []Target {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	h.expire(now)
+	if h.pushed == nil {
+		h.pushed = make(map[string]*alertState)
+	}
+	st := h.pushed[key]
+	if st == nil {
+		st = &alertState{}
+		h.pushed[key] = st
+	}
+	st.seen = now
+	var claimed []Target
+	for _, t := range targets {
+		if st.find(t) < 0 {
+			st.pushes = append(st.pushes, alertPush{target: t})
+			claimed = append(claimed, t)
+		}
+	}
+	return claimed
+}
+
+// expire forgets the alerts last received more than Expiry before now.
+// h.mu must be held.
+func (h *AlertmanagerHandler) expire(now time.Time) {
+	expiry := h.Expiry
+	if expiry <= 0 {
+		expiry = 24 * time.Hour
+	}
+	for key, st := range h.pushed {
+		if now.Sub(st.seen) > expiry {
+			delete(h.pushed, key)
+		}
+	}
+}
+
+// record stores the iden of the push of an alert to t, or forgets about the
+// push if it failed so that it is tried again when the alert repeats.
+func (h *AlertmanagerHandler) record(key string, t Target, iden string, ok bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := h.pushed[key]
+	if st == nil {
+		return
+	}
+	i := st.find(t)
+	if i < 0 {
+		return
+	}
+	if ok {
+		st.pushes[i].iden = iden
+		return
+	}
+	st.pushes = append(st.pushes[:i:i], st.pushes[i+1:]...)
+	if len(st.pushes) == 0 {
+		delete(h.pushed, key)
+	}
+}
+
+// find returns the index of the push to t, or -1.
+func (st *alertState) find(t Target) int {
+	for i, p := range st.pushes {
+		if p.target == t {
+			return i
+		}
+	}
+	return -1
+}
+
+func (h *AlertmanagerHandler) resolve(a *Alert) error {
+	h.mu.Lock()
+	var pushes []alertPush
+	if st := h.pushed[a.key()]; st != nil {
+		pushes = st.pushes
+	}
+	delete(h.pushed, a.key())
+	h.mu.Unlock()
+
+	var firstErr error
+	for _, p := range pushes {
+		if p.iden == "" {
+			continue
+		}
+		if err := h.Client.DismissPush(p.iden); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if h.NotifyResolved {
+		title, body := alertText(a)
+		for _, t := range h.targets(a) {
+			if _, err := h.Client.Push("/pushes", t.note(title, body)); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// alertText renders the title and body of the push for an alert.
+func alertText(a *Alert) (string, string) {
+	name := a.Labels["alertname"]
+	if name == "" {
+		name = "Alert"
+	}
+	title := "[" + strings.ToUpper(a.Status) + "] " + name
+
+	var lines []string
+	if s := a.Annotations["summary"]; s != "" {
+		lines = append(lines, s)
+	}
+	if s := a.Annotations["description"]; s != "" {
+		lines = append(lines, s)
+	}
+	names := make([]string, 0, len(a.Labels))
+	for name := range a.Labels {
+		if name != "alertname" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(lines) > 0 && len(names) > 0 {
+		lines = append(lines, "")
+	}
+	for _, name := range names {
+		lines = append(lines, name+"="+a.Labels[name])
+	}
+	return title, strings.Join(lines, "\n")
+}
@@ -0,0 +1,190 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type alertServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	pushes    []map[string]interface{}
+	dismissed []string
+}
+
+func AlertResponseStub() *alertServer {
+	s := &alertServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if iden := strings.TrimPrefix(r.URL.Path, "/pushes/"); iden != r.URL.Path {
+			if body["dismissed"] != true {
+				http.Error(w, "Bad Request", http.StatusBadRequest)
+				return
+			}
+			s.dismissed = append(s.dismissed, iden)
+			w.Write([]byte(`{}`))
+			return
+		}
+		s.pushes = append(s.pushes, body)
+		w.Write([]byte(`{"iden": "push` + string(rune('0'+len(s.pushes))) + `"}`))
+	}))
+	return s
+}
+
+const firingWebhook = `{
+  "version": "4",
+  "status": "firing",
+  "alerts": [
+    {
+      "status": "firing",
+      "labels": {"alertname": "DiskFull", "team": "ops", "instance": "db1"},
+      "annotations": {"summary": "Disk is full"},
+      "generatorURL": "http://prometheus/graph",
+      "fingerprint": "abc"
+    },
+    {
+      "status": "firing",
+      "labels": {"alertname": "HighLoad", "team": "web"},
+      "fingerprint": "def"
+    }
+  ]
+}`
+
+func TestAlertmanagerHandler(t *testing.T) {
+	server := AlertResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	h := NewAlertmanagerHandler(pb)
+	h.Routes = []AlertRoute{
+		{Match: map[string]string{"team": "ops"}, Targets: []Target{DeviceTarget("phone"), ChannelTarget("ops")}},
+	}
+	h.Default = []Target{EmailTarget("web@example.com")}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(firingWebhook)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	if assert.Len(t, server.pushes, 3) {
+		assert.Equal(t, "link", server.pushes[0]["type"])
+		assert.Equal(t, "phone", server.pushes[0]["device_iden"])
+		assert.Equal(t, "[FIRING] DiskFull", server.pushes[0]["title"])
+		assert.Equal(t, "Disk is full\n\ninstance=db1\nteam=ops", server.pushes[0]["body"])
+		assert.Equal(t, "http://prometheus/graph", server.pushes[0]["url"])
+		assert.Equal(t, "ops", server.pushes[1]["channel_tag"])
+		assert.Equal(t, "note", server.pushes[2]["type"])
+		assert.Equal(t, "web@example.com", server.pushes[2]["email"])
+	}
+
+	resolved := strings.Replace(firingWebhook, `"status": "firing"`, `"status": "resolved"`, -1)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(resolved)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []string{"push1", "push2", "push3"}, server.dismissed)
+	assert.Len(t, server.pushes, 3)
+}
+
+func TestAlertmanagerHandlerErrors(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	h := NewAlertmanagerHandler(pb)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader("{")))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(firingWebhook)))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Contains(t, rec.Body.String(), e.Message)
+}
+
+func TestAlertmanagerHandlerRepeat(t *testing.T) {
+	server := AlertResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	h := NewAlertmanagerHandler(pb)
+
+	// Alertmanager sends alerts again while they keep firing.
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(firingWebhook)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	assert.Len(t, server.pushes, 2)
+
+	resolved := strings.Replace(firingWebhook, `"status": "firing"`, `"status": "resolved"`, -1)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(resolved)))
+	assert.Equal(t, []string{"push1", "push2"}, server.dismissed)
+
+	// Once resolved, the alert is pushed again when it fires.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(firingWebhook)))
+	assert.Len(t, server.pushes, 4)
+}
+
+func TestAlertmanagerHandlerRetryFailed(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	pb := New(k)
+	pb.Retries = 0
+	pb.Endpoint.URL = server.URL
+	h := NewAlertmanagerHandler(pb)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(firingWebhook)))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	server.Close()
+
+	// Failed pushes are made when Alertmanager retries the webhook.
+	ok := AlertResponseStub()
+	defer ok.Close()
+	pb.Endpoint.URL = ok.URL
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(firingWebhook)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, ok.pushes, 2)
+}
+
+func TestAlertmanagerHandlerExpiry(t *testing.T) {
+	server := AlertResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	h := NewAlertmanagerHandler(pb)
+	h.Expiry = 200 * time.Millisecond
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(firingWebhook)))
+	assert.Len(t, h.pushed, 2)
+
+	// The resolve never arrives; the alerts are forgotten on the next
+	// webhook.
+	time.Sleep(300 * time.Millisecond)
+	other := strings.Replace(strings.Replace(firingWebhook, "abc", "ghi", 1), "def", "jkl", 1)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/", strings.NewReader(other)))
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	assert.Len(t, h.pushed, 2)
+	assert.Contains(t, h.pushed, "ghi")
+	assert.Contains(t, h.pushed, "jkl")
+}
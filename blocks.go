@@ -0,0 +1,50 @@
+package pushbullet
+
+import (
+	"net/url"
+)
+
+// A Block stops a user from pushing to or chatting with the account.
+type Block struct {
+	Iden     string     `json:"iden"`
+	Active   bool       `json:"active"`
+	Created  Timestamp  `json:"created"`
+	Modified Timestamp  `json:"modified"`
+	User     *BlockUser `json:"user"`
+}
+
+// BlockUser describes the blocked user.
+type BlockUser struct {
+	Iden            string `json:"iden,omitempty"`
+	Email           string `json:"email"`
+	EmailNormalized string `json:"email_normalized"`
+	Name            string `json:"name"`
+	ImageUrl        string `json:"image_url"`
+}
+
+type blockResponse struct {
+	Blocks []*Block
+}
+
+// Blocks fetches the users the account has blocked.
+func (c *Client) Blocks() ([]*Block, error) {
+	blockResp, err := get[blockResponse](c, "/blocks", nil)
+	if err != nil {
+		return nil, err
+	}
+	return blockResp.Blocks, nil
+}
+
+// Block blocks the user with the given email address and returns the new
+// block.
+func (c *Client) Block(email string) (*Block, error) {
+	data := struct {
+		Email string `json:"email"`
+	}{email}
+	return post[Block](c, "/blocks", data)
+}
+
+// Unblock removes the block with the given iden.
+func (c *Client) Unblock(iden string) error {
+	return c.exec("DELETE", "/blocks/"+url.PathEscape(iden), nil, nil, nil)
+}
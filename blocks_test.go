@@ -0,0 +1,69 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var bl = &Block{
+	Iden:     "ujpah72o0sjAoRtnM0jc",
+	Active:   true,
+	Created:  ts("1412047948.579029"),
+	Modified: ts("1412047948.579031"),
+	User: &BlockUser{
+		Email:           "spam@example.com",
+		EmailNormalized: "spam@example.com",
+		Name:            "Spammer",
+		ImageUrl:        "https://static.pushbullet.com/missing-image/55a7dc-45",
+	},
+}
+
+func TestBlocks(t *testing.T) {
+	b, _ := json.Marshal(bl)
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, `{"blocks": [`+string(b)+`]}`)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	blocks, err := pb.Blocks()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Block{bl}, blocks)
+
+	assert.NoError(t, pb.Unblock(bl.Iden))
+	assert.Equal(t, []recordedRequest{
+		{"GET", "/blocks", ""},
+		{"DELETE", "/blocks/" + bl.Iden, ""},
+	}, reqs)
+}
+
+func TestBlock(t *testing.T) {
+	b, _ := json.Marshal(bl)
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, string(b))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	block, err := pb.Block("spam@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, bl, block)
+	assert.Equal(t, "POST", reqs[0].Method)
+	assert.Equal(t, "/blocks", reqs[0].URI)
+	assert.JSONEq(t, `{"email": "spam@example.com"}`, reqs[0].Body)
+}
+
+func TestBlocksError(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := pb.Blocks()
+	assert.Equal(t, e, err)
+	_, err = pb.Block("spam@example.com")
+	assert.Equal(t, e, err)
+}
@@ -0,0 +1,34 @@
+package pushbullet
+
+// A Chat is a conversation with another PushBullet user or email address.
+type Chat struct {
+	Iden     string    `json:"iden"`
+	Active   bool      `json:"active"`
+	Created  Timestamp `json:"created"`
+	Modified Timestamp `json:"modified"`
+	Muted    bool      `json:"muted"`
+	With     *ChatWith `json:"with"`
+}
+
+// ChatWith describes the other party of a Chat.
+type ChatWith struct {
+	Type            string `json:"type"`
+	Iden            string `json:"iden,omitempty"`
+	Email           string `json:"email"`
+	EmailNormalized string `json:"email_normalized"`
+	Name            string `json:"name"`
+	ImageUrl        string `json:"image_url"`
+}
+
+type chatResponse struct {
+	Chats []*Chat
+}
+
+// Chats fetches the user's chats from PushBullet.
+func (c *Client) Chats() ([]*Chat, error) {
+	chatResp, err := get[chatResponse](c, "/chats", nil)
+	if err != nil {
+		return nil, err
+	}
+	return chatResp.Chats, nil
+}
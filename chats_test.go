@@ -0,0 +1,47 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ch = &Chat{
+	Iden:     "ujlMns72k",
+	Active:   true,
+	Created:  ts("1412047948.579029"),
+	Modified: ts("1412047948.579031"),
+	With: &ChatWith{
+		Type:            "user",
+		Email:           "carmack@idsoftware.com",
+		EmailNormalized: "carmack@idsoftware.com",
+		Name:            "John Carmack",
+		ImageUrl:        "https://static.pushbullet.com/missing-image/55a7dc-45",
+	},
+}
+
+func TestChats(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ch, _ := json.Marshal(ch)
+		w.Write([]byte(`{"chats": [` + string(ch) + `]}`))
+	}))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	chats, err := pb.Chats()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Chat{ch}, chats)
+}
+
+func TestChatsError(t *testing.T) {
+	server := PushbulletErrResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	_, err := pb.Chats()
+	assert.Equal(t, "500 Internal Server Error", err.Error())
+}
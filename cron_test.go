@@ -0,0 +1,38 @@
+package pushbullet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronNext(t *testing.T) {
+	// Sunday, 18 October 2026
+	now := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"* * * * *":       time.Date(2026, 10, 18, 9, 31, 0, 0, time.UTC),
+		"0 8 * * mon":     time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC),
+		"*/15 * * * *":    time.Date(2026, 10, 18, 9, 45, 0, 0, time.UTC),
+		"0 9-17 * * 1-5":  time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC),
+		"30 6 1 jan *":    time.Date(2027, 1, 1, 6, 30, 0, 0, time.UTC),
+		"0 0 13 * fri":    time.Date(2026, 10, 23, 0, 0, 0, 0, time.UTC),
+		"0 12 * * 7":      time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+		"@daily":          time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		"0 0 29 feb *":    time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		"5,10 10 18 10 *": time.Date(2026, 10, 18, 10, 5, 0, 0, time.UTC),
+	}
+	for spec, want := range cases {
+		cron, err := parseCron(spec)
+		if assert.NoError(t, err, spec) {
+			assert.Equal(t, want, cron.next(now), spec)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "x * * * *"} {
+		_, err := parseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -11,7 +11,12 @@ func main() {
 		panic(err)
 	}
 
-	err = pb.PushNote(devs[0].Iden, "Hello!", "Hi from go-pushbullet!")
+	push, err := pb.PushNote(devs[0].Iden, "Hello!", "Hi from go-pushbullet!")
+	if err != nil {
+		panic(err)
+	}
+
+	err = pb.DismissPush(push.Iden)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// The completion scripts call "pushb __complete" with the words typed so far,
+// the last one being the word to complete, and fall back to file names when
+// it prints nothing.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for pushb
+_pushb() {
+	local IFS=$'\n' c
+	COMPREPLY=()
+	for c in $(pushb __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null); do
+		COMPREPLY+=("$(printf '%q' "$c")")
+	done
+}
+complete -o default -F _pushb pushb
+`,
+	"zsh": `#compdef pushb
+# zsh completion for pushb
+_pushb() {
+	local -a candidates
+	candidates=("${(@f)$(pushb __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	if (( ${#candidates} )) && [[ -n ${candidates[1]} ]]; then
+		compadd -a candidates
+	else
+		_files
+	fi
+}
+compdef _pushb pushb
+`,
+	"fish": `# fish completion for pushb
+function __pushb_complete
+	set -l args (commandline -opc)
+	set -e args[1]
+	pushb __complete $args (commandline -ct) 2>/dev/null
+end
+complete -c pushb -f -a '(__pushb_complete)'
+complete -c pushb -n '__fish_seen_subcommand_from file pipe' -F
+`,
+}
+
+var completionCmd = &command{
+	name:  "completion",
+	args:  "bash | zsh | fish",
+	short: "Prints a shell completion script",
+	run: func(fs *flag.FlagSet) error {
+		script, ok := completionScripts[fs.Arg(0)]
+		if fs.NArg() != 1 || !ok {
+			return errUsage
+		}
+		fmt.Print(script)
+		return nil
+	},
+}
+
+// complete prints the completions of the last of words, one per line.
+func complete(words []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	for _, c := range completions(words[:len(words)-1], cur) {
+		if strings.HasPrefix(c, cur) {
+			fmt.Println(c)
+		}
+	}
+}
+
+// completions returns the candidates for the word following prev.
+func completions(prev []string, cur string) []string {
+	i := 0
+	for i < len(prev) && strings.HasPrefix(prev[i], "-") {
+		switch strings.TrimLeft(prev[i], "-") {
+		case "p", "profile":
+			if i+1 == len(prev) {
+				return cachedProfiles()
+			}
+			profileName = prev[i+1]
+			i += 2
+		default:
+			i++
+		}
+	}
+	if i == len(prev) {
+		if strings.HasPrefix(cur, "-") {
+			return []string{"-profile", "-p"}
+		}
+		return commandNames()
+	}
+
+	name, rest := prev[i], prev[i+1:]
+	if name == "help" {
+		if len(rest) == 0 {
+			return commandNames()
+		}
+		return nil
+	}
+	cmd := findCommand(name)
+	if cmd == nil {
+		return nil
+	}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+
+	// Like the flag package, stop at the first argument that is no flag.
+	var args []string
+	for j := 0; j < len(rest); j++ {
+		w := rest[j]
+		if w == "--" {
+			args = rest[j+1:]
+			break
+		}
+		if !strings.HasPrefix(w, "-") || w == "-" {
+			args = rest[j:]
+			break
+		}
+		f := fs.Lookup(strings.TrimLeft(w, "-"))
+		if f == nil || isBoolFlag(f) {
+			continue
+		}
+		if j+1 == len(rest) {
+			return flagValues(f.Name)
+		}
+		j++
+	}
+
+	if strings.HasPrefix(cur, "-") && args == nil {
+		var flags []string
+		fs.VisitAll(func(f *flag.Flag) {
+			flags = append(flags, "-"+f.Name)
+		})
+		return flags
+	}
+	return argValues(cmd.name, args)
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func commandNames() []string {
+	names := []string{"help"}
+	for _, cmd := range commands {
+		names = append(names, cmd.name)
+	}
+	return names
+}
+
+// flagValues returns the candidates for the value of the named flag.
+func flagValues(name string) []string {
+	switch name {
+	case "device", "d":
+		var names []string
+		for _, d := range cachedProfile().Devices {
+			names = append(names, d.Name)
+		}
+		return names
+	case "channel", "c":
+		return cachedProfile().Channels
+	case "email", "e":
+		return cachedProfile().Chats
+	case "store":
+		return []string{storePlain, storeSecretService, storeFile}
+	}
+	return nil
+}
+
+// argValues returns the candidates for the argument following args.
+func argValues(cmd string, args []string) []string {
+	switch {
+	case cmd == "pushes" && len(args) == 0:
+		return []string{"list", "dismiss", "delete"}
+	case cmd == "blocks" && len(args) == 0:
+		return []string{"list", "add", "remove"}
+	case cmd == "profiles" && len(args) == 0:
+		return []string{"list", "use", "remove"}
+	case cmd == "profiles" && len(args) == 1 && args[0] != "list":
+		return cachedProfiles()
+	case cmd == "completion" && len(args) == 0:
+		return []string{"bash", "zsh", "fish"}
+	}
+	return nil
+}
+
+// cachedProfile returns the selected profile without contacting the server.
+func cachedProfile() *Profile {
+	cfg, err := readConfig()
+	if err != nil || cfg.Profiles[cfg.current()] == nil {
+		return &Profile{}
+	}
+	return cfg.Profiles[cfg.current()]
+}
+
+func cachedProfiles() []string {
+	cfg, err := readConfig()
+	if err != nil {
+		return nil
+	}
+	return cfg.names()
+}
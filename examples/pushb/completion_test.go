@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pushb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "pushb"), 0700))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pushb", "config.json"), []byte(`{
+		"default": "home",
+		"profiles": {
+			"home": {"devices": [{"iden": "a", "name": "Phone"}], "channels": ["news"]},
+			"work": {"devices": [{"iden": "b", "name": "Laptop"}], "chats": ["boss@example.com"]}
+		}
+	}`), 0600))
+	defer func() { profileName = "" }()
+
+	cases := []struct {
+		prev []string
+		cur  string
+		want []string
+	}{
+		{nil, "", commandNames()},
+		{nil, "-", []string{"-profile", "-p"}},
+		{[]string{"-p"}, "", []string{"home", "work"}},
+		{[]string{"help"}, "", commandNames()},
+		{[]string{"help", "note"}, "", nil},
+		{[]string{"bogus"}, "", nil},
+		{[]string{"note", "-device"}, "", []string{"Phone"}},
+		{[]string{"note", "--channel"}, "", []string{"news"}},
+		{[]string{"-profile", "work", "note", "-d"}, "", []string{"Laptop"}},
+		{[]string{"-p", "work", "note", "-e"}, "", []string{"boss@example.com"}},
+		// A flag value is skipped, and a bool flag takes none.
+		{[]string{"note", "-d", "Phone"}, "-", []string{"-c", "-channel", "-d", "-device", "-e", "-email"}},
+		{[]string{"pipe", "-file"}, "-", []string{"-c", "-channel", "-chunks", "-d", "-device", "-e", "-email", "-file", "-lines", "-title"}},
+		// Flags end at the first argument or at "--".
+		{[]string{"note", "Title"}, "-", nil},
+		{[]string{"pipe", "--"}, "-", nil},
+		{[]string{"pipe", "--", "-d"}, "", nil},
+		{[]string{"pushes"}, "", []string{"list", "dismiss", "delete"}},
+		{[]string{"pushes", "-"}, "", nil},
+		{[]string{"profiles", "use"}, "", []string{"home", "work"}},
+		{[]string{"profiles", "list"}, "", nil},
+		{[]string{"completion"}, "", []string{"bash", "zsh", "fish"}},
+		{[]string{"login", "-store"}, "", []string{storePlain, storeSecretService, storeFile}},
+	}
+	for _, c := range cases {
+		profileName = ""
+		assert.Equal(t, c.want, completions(c.prev, c.cur), "%q %q", c.prev, c.cur)
+	}
+}
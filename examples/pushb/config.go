@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+
+	"github.com/xconstruct/go-pushbullet"
+)
+
+// defaultProfile is the profile used when none is selected or configured.
+const defaultProfile = "default"
+
+// validProfileName matches the profile names pushb accepts. Names are used in
+// file names, so they must not contain path separators or dots.
+var validProfileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// checkProfileName returns an error if name is not a valid profile name.
+func checkProfileName(name string) error {
+	if !validProfileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, - and _", name)
+	}
+	return nil
+}
+
+// Config is the pushb configuration. It holds a profile for each account.
+type Config struct {
+	Default  string              `json:"default,omitempty"`
+	Profiles map[string]*Profile `json:"profiles"`
+
+	// The single account of the old ~/.pushb.config.json format.
+	ApiKey  string   `json:"api_key,omitempty"`
+	Devices []Device `json:"devices,omitempty"`
+}
+
+// A Profile is the configuration of a single account.
+type Profile struct {
+	// ApiKey is the api key, unless it is kept in Store.
+	ApiKey  string   `json:"api_key,omitempty"`
+	Store   string   `json:"store,omitempty"`
+	Devices []Device `json:"devices"`
+
+	// Channel tags and chat emails, cached for shell completion.
+	Channels []string `json:"channels,omitempty"`
+	Chats    []string `json:"chats,omitempty"`
+}
+
+type Device struct {
+	Iden string `json:"iden"`
+	Name string `json:"name"`
+}
+
+// current returns the name of the selected profile.
+func (cfg *Config) current() string {
+	switch {
+	case profileName != "":
+		return profileName
+	case cfg.Default != "":
+		return cfg.Default
+	}
+	return defaultProfile
+}
+
+// names returns the sorted profile names.
+func (cfg *Config) names() []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func home() string {
+	home := os.Getenv("HOME")
+	if runtime.GOOS == "windows" && home == "" {
+		home = os.Getenv("USERPROFILE")
+	}
+	return home
+}
+
+// configDir returns the directory of the pushb configuration, following the
+// XDG base directory specification.
+func configDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "pushb")
+	}
+	if runtime.GOOS == "windows" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			return filepath.Join(dir, "pushb")
+		}
+	}
+	return filepath.Join(home(), ".config", "pushb")
+}
+
+func configPath() string {
+	return filepath.Join(configDir(), "config.json")
+}
+
+func legacyConfigPath() string {
+	return filepath.Join(home(), ".pushb.config.json")
+}
+
+// readConfig reads the config, falling back to the old single account
+// config file. A missing config is not an error.
+func readConfig() (*Config, error) {
+	cfg := &Config{}
+	b, err := ioutil.ReadFile(configPath())
+	if os.IsNotExist(err) {
+		b, err = ioutil.ReadFile(legacyConfigPath())
+	}
+	if os.IsNotExist(err) {
+		b, err = []byte("{}"), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*Profile)
+	}
+	if cfg.ApiKey != "" || len(cfg.Devices) > 0 {
+		if cfg.Profiles[defaultProfile] == nil {
+			cfg.Profiles[defaultProfile] = &Profile{ApiKey: cfg.ApiKey, Devices: cfg.Devices}
+		}
+		cfg.ApiKey, cfg.Devices = "", nil
+	}
+	return cfg, nil
+}
+
+// writeConfig replaces the config file. An old single account config file
+// is removed, as its contents were migrated by readConfig.
+func writeConfig(cfg *Config) error {
+	path := configPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(b, '\n'), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Remove(legacyConfigPath()); err == nil {
+		log.Printf("migrated %s to %s", legacyConfigPath(), path)
+	}
+	return nil
+}
+
+// newClient returns a client for the selected profile. PUSHBULLET_TOKEN
+// overrides the configured api key; its account may differ from the
+// profile's, so the cached device list is not used then.
+func newClient() (*pushbullet.Client, *Profile, error) {
+	if key := os.Getenv("PUSHBULLET_TOKEN"); key != "" {
+		return pushbullet.New(key), &Profile{}, nil
+	}
+
+	cfg, err := readConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading config: %v", err)
+	}
+	name := cfg.current()
+	prof := cfg.Profiles[name]
+	if prof == nil {
+		return nil, nil, fmt.Errorf("no profile %q configured (run \"pushb -profile %s login\" first)", name, name)
+	}
+	key, err := loadKey(name, prof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("profile %q: %v", name, err)
+	}
+	if key == "" {
+		return nil, nil, errors.New("no api key configured (run \"pushb login\" first)")
+	}
+	return pushbullet.New(key), prof, nil
+}
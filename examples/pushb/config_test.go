@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckProfileName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"default":    true,
+		"work-2":     true,
+		"my_phone":   true,
+		"":           false,
+		"..":         false,
+		"../../x":    false,
+		"a/b":        false,
+		`a\b`:        false,
+		"with.dot":   false,
+		"with space": false,
+	} {
+		assert.Equal(t, valid, checkProfileName(name) == nil, name)
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Places to keep an api key in. With storePlain it is written to the config.
+const (
+	storePlain         = "plain"
+	storeSecretService = "secret-service"
+	storeFile          = "file"
+)
+
+// pbkdf2Iterations is the work factor for deriving file encryption keys.
+const pbkdf2Iterations = 600000
+
+func validStore(store string) bool {
+	switch store {
+	case storePlain, storeSecretService, storeFile:
+		return true
+	}
+	return false
+}
+
+// loadKey returns the api key of the named profile.
+func loadKey(name string, prof *Profile) (string, error) {
+	switch prof.Store {
+	case "", storePlain:
+		return prof.ApiKey, nil
+	case storeSecretService:
+		out, err := secretTool(nil, "lookup", "service", "pushb", "profile", name)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(out)), nil
+	case storeFile:
+		return readKeyFile(name)
+	}
+	return "", fmt.Errorf("unknown key store %q", prof.Store)
+}
+
+// saveKey stores key for the named profile in store and records it in prof.
+func saveKey(name string, prof *Profile, store, key string) error {
+	switch store {
+	case storePlain:
+		prof.ApiKey = key
+	case storeSecretService:
+		label := "Pushbullet api key (pushb profile " + name + ")"
+		if _, err := secretTool(strings.NewReader(key), "store", "--label="+label, "service", "pushb", "profile", name); err != nil {
+			return err
+		}
+		prof.ApiKey = ""
+	case storeFile:
+		if err := writeKeyFile(name, key); err != nil {
+			return err
+		}
+		prof.ApiKey = ""
+	default:
+		return fmt.Errorf("unknown key store %q", store)
+	}
+	prof.Store = store
+	return nil
+}
+
+// deleteKey removes the api key of the named profile from its store.
+func deleteKey(name string, prof *Profile) error {
+	var err error
+	switch prof.Store {
+	case storeSecretService:
+		_, err = secretTool(nil, "clear", "service", "pushb", "profile", name)
+	case storeFile:
+		var path string
+		if path, err = keyFilePath(name); err == nil {
+			err = os.Remove(path)
+		}
+		if os.IsNotExist(err) {
+			err = nil
+		}
+	}
+	prof.ApiKey = ""
+	return err
+}
+
+// secretTool runs secret-tool, the command line interface of the
+// freedesktop Secret Service shipped with libsecret.
+func secretTool(stdin *strings.Reader, args ...string) ([]byte, error) {
+	path, err := exec.LookPath("secret-tool")
+	if err != nil {
+		return nil, errors.New("secret-tool not found; install libsecret's tools to use the secret service")
+	}
+	cmd := exec.Command(path, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("secret-tool: %s", msg)
+		}
+		if args[0] == "lookup" {
+			return nil, errors.New("api key not found in the secret service")
+		}
+		return nil, fmt.Errorf("secret-tool: %v", err)
+	}
+	return out, nil
+}
+
+// An encryptedKey is the contents of a key file. The api key is sealed with
+// AES-256-GCM under a key derived from a passphrase.
+type encryptedKey struct {
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// keyFilePath returns the path of the key file of the named profile. The name
+// is checked again here so that no key file ends up outside the config
+// directory.
+func keyFilePath(name string) (string, error) {
+	if err := checkProfileName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir(), "keys", name+".json"), nil
+}
+
+func writeKeyFile(name, key string) error {
+	pass, err := passphrase(true)
+	if err != nil {
+		return err
+	}
+
+	ek := encryptedKey{
+		Iterations: pbkdf2Iterations,
+		Salt:       make([]byte, 16),
+	}
+	if _, err := rand.Read(ek.Salt); err != nil {
+		return err
+	}
+	aead, err := newAEAD(pass, ek.Salt, ek.Iterations)
+	if err != nil {
+		return err
+	}
+	ek.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(ek.Nonce); err != nil {
+		return err
+	}
+	ek.Ciphertext = aead.Seal(nil, ek.Nonce, []byte(key), []byte(name))
+
+	b, err := json.Marshal(ek)
+	if err != nil {
+		return err
+	}
+	path, err := keyFilePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+func readKeyFile(name string) (string, error) {
+	path, err := keyFilePath(name)
+	if err != nil {
+		return "", err
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var ek encryptedKey
+	if err := json.Unmarshal(b, &ek); err != nil {
+		return "", err
+	}
+
+	pass, err := passphrase(false)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(pass, ek.Salt, ek.Iterations)
+	if err != nil {
+		return "", err
+	}
+	if len(ek.Nonce) != aead.NonceSize() {
+		return "", errors.New("invalid key file")
+	}
+	key, err := aead.Open(nil, ek.Nonce, ek.Ciphertext, []byte(name))
+	if err != nil {
+		return "", errors.New("wrong passphrase")
+	}
+	return string(key), nil
+}
+
+func newAEAD(pass string, salt []byte, iter int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, pass, salt, iter, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// passphrase returns the passphrase for key files from PUSHB_PASSPHRASE or
+// asks for it, twice if confirm is set.
+func passphrase(confirm bool) (string, error) {
+	if pass := os.Getenv("PUSHB_PASSPHRASE"); pass != "" {
+		return pass, nil
+	}
+	pass, err := readSecret("Passphrase: ")
+	if err != nil {
+		return "", err
+	}
+	if pass == "" {
+		return "", errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := readSecret("Repeat passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != pass {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return pass, nil
+}
+
+// readSecret prompts for a line on the terminal without echoing it. Where
+// there is no terminal it reads a line from standard input.
+func readSecret(prompt string) (string, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		fmt.Fprint(os.Stderr, prompt)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), ignoreEOF(line, err)
+	}
+	defer tty.Close()
+
+	stty := func(arg string) {
+		cmd := exec.Command("stty", arg)
+		cmd.Stdin = tty
+		cmd.Run()
+	}
+	fmt.Fprint(tty, prompt)
+	stty("-echo")
+	line, err := bufio.NewReader(tty).ReadString('\n')
+	stty("echo")
+	fmt.Fprintln(tty)
+	return strings.TrimRight(line, "\r\n"), ignoreEOF(line, err)
+}
+
+// ignoreEOF drops the error of a final line without a newline.
+func ignoreEOF(line string, err error) error {
+	if err == io.EOF && line != "" {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xconstruct/go-pushbullet"
+)
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+}
+
+func formatTime(t pushbullet.Timestamp) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04")
+}
+
+var devicesAll bool
+
+var devicesCmd = &command{
+	name:  "devices",
+	short: "Shows a list of registered devices",
+	flags: func(fs *flag.FlagSet) {
+		fs.BoolVar(&devicesAll, "a", false, "include deleted devices")
+	},
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() != 0 {
+			return errUsage
+		}
+		pb, _, err := newClient()
+		if err != nil {
+			return err
+		}
+		devs, err := pb.Devices()
+		if err != nil {
+			return err
+		}
+		if !devicesAll {
+			devs = pushbullet.FilterDevices(devs, pushbullet.ActiveDevices)
+		}
+
+		w := newTable()
+		fmt.Fprintln(w, "IDEN\tNAME\tMODEL\tFLAGS")
+		for _, d := range devs {
+			var flags []string
+			if d.Shared {
+				flags = append(flags, "shared")
+			}
+			if d.HasSms {
+				flags = append(flags, "sms")
+			}
+			if !d.Active {
+				flags = append(flags, "deleted")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.Iden, d.Name(), d.Model, strings.Join(flags, ","))
+		}
+		return w.Flush()
+	},
+}
+
+var pushesLimit int
+
+var pushesCmd = &command{
+	name:  "pushes",
+	args:  "[list | dismiss IDEN... | delete IDEN...]",
+	short: "Lists, dismisses or deletes pushes",
+	flags: func(fs *flag.FlagSet) {
+		fs.IntVar(&pushesLimit, "n", 20, "number of pushes to list")
+	},
+	run: func(fs *flag.FlagSet) error {
+		action := fs.Arg(0)
+		idens := fs.Args()
+		if len(idens) > 0 {
+			idens = idens[1:]
+		}
+		pb, _, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		switch action {
+		case "", "list":
+			if len(idens) > 0 {
+				return errUsage
+			}
+			return listPushes(pb)
+		case "dismiss", "delete":
+			if len(idens) == 0 {
+				return errUsage
+			}
+			for _, iden := range idens {
+				if action == "dismiss" {
+					err = pb.DismissPush(iden)
+				} else {
+					err = pb.DeletePush(iden)
+				}
+				if err != nil {
+					return fmt.Errorf("%s: %v", iden, err)
+				}
+			}
+			return nil
+		}
+		return errUsage
+	},
+}
+
+func listPushes(pb *pushbullet.Client) error {
+	pushes, err := pb.Pushes(pushesLimit)
+	if err != nil {
+		return err
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "IDEN\tCREATED\tTYPE\tFROM\tTITLE")
+	for _, p := range pushes {
+		title := p.Title
+		if title == "" {
+			title = p.FileName
+		}
+		if title == "" {
+			title = p.URL
+		}
+		if p.Dismissed {
+			title += " (dismissed)"
+		}
+		from := p.SenderName
+		if from == "" {
+			from = p.SenderEmail
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Iden, formatTime(p.Created), p.Type, from, title)
+	}
+	return w.Flush()
+}
+
+var subscriptionsCmd = &command{
+	name:  "subscriptions",
+	short: "Shows the channels you are subscribed to",
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() != 0 {
+			return errUsage
+		}
+		pb, _, err := newClient()
+		if err != nil {
+			return err
+		}
+		subs, err := pb.Subscriptions()
+		if err != nil {
+			return err
+		}
+
+		w := newTable()
+		fmt.Fprintln(w, "TAG\tNAME\tMUTED")
+		for _, s := range subs {
+			if !s.Active || s.Channel == nil {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", s.Channel.Tag, s.Channel.Name, s.Muted)
+		}
+		return w.Flush()
+	},
+}
+
+var chatsCmd = &command{
+	name:  "chats",
+	short: "Shows the people you have chats with",
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() != 0 {
+			return errUsage
+		}
+		pb, _, err := newClient()
+		if err != nil {
+			return err
+		}
+		chats, err := pb.Chats()
+		if err != nil {
+			return err
+		}
+
+		w := newTable()
+		fmt.Fprintln(w, "EMAIL\tNAME\tMUTED")
+		for _, c := range chats {
+			if !c.Active || c.With == nil {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%v\n", c.With.Email, c.With.Name, c.Muted)
+		}
+		return w.Flush()
+	},
+}
+
+var blocksCmd = &command{
+	name:  "blocks",
+	args:  "[list | add EMAIL... | remove EMAIL|IDEN...]",
+	short: "Lists, blocks or unblocks users",
+	run: func(fs *flag.FlagSet) error {
+		action := fs.Arg(0)
+		args := fs.Args()
+		if len(args) > 0 {
+			args = args[1:]
+		}
+		pb, _, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		switch action {
+		case "", "list":
+			if len(args) > 0 {
+				return errUsage
+			}
+			return listBlocks(pb)
+		case "add":
+			if len(args) == 0 {
+				return errUsage
+			}
+			for _, email := range args {
+				b, err := pb.Block(email)
+				if err != nil {
+					return fmt.Errorf("%s: %v", email, err)
+				}
+				fmt.Println(b.Iden)
+			}
+			return nil
+		case "remove":
+			if len(args) == 0 {
+				return errUsage
+			}
+			blocks, err := pb.Blocks()
+			if err != nil {
+				return err
+			}
+			for _, arg := range args {
+				if err := pb.Unblock(blockIden(blocks, arg)); err != nil {
+					return fmt.Errorf("%s: %v", arg, err)
+				}
+			}
+			return nil
+		}
+		return errUsage
+	},
+}
+
+func listBlocks(pb *pushbullet.Client) error {
+	blocks, err := pb.Blocks()
+	if err != nil {
+		return err
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "IDEN\tCREATED\tEMAIL\tNAME")
+	for _, b := range blocks {
+		if !b.Active || b.User == nil {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", b.Iden, formatTime(b.Created), b.User.Email, b.User.Name)
+	}
+	return w.Flush()
+}
+
+// blockIden returns the iden of the active block of the user with the email
+// address s, or s itself if there is none.
+func blockIden(blocks []*pushbullet.Block, s string) string {
+	for _, b := range blocks {
+		if b.Active && b.User != nil && (strings.EqualFold(b.User.Email, s) || strings.EqualFold(b.User.EmailNormalized, s)) {
+			return b.Iden
+		}
+	}
+	return s
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/xconstruct/go-pushbullet"
+)
+
+var loginStore string
+
+var loginCmd = &command{
+	name:  "login",
+	args:  "[API_KEY]",
+	short: "Saves the api key and caches devices, channels and chats in the selected profile",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&loginStore, "store", "", "where to keep the api key: plain (in the config), secret-service or file (encrypted with a passphrase); defaults to the profile's current store")
+	},
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() > 1 {
+			return errUsage
+		}
+		if loginStore != "" && !validStore(loginStore) {
+			return errUsage
+		}
+		cfg, err := readConfig()
+		if err != nil {
+			return err
+		}
+		name := cfg.current()
+		if err := checkProfileName(name); err != nil {
+			return err
+		}
+
+		key := fs.Arg(0)
+		if key == "" {
+			if key, err = readSecret("Api key: "); err != nil {
+				return err
+			}
+		}
+		if key == "" {
+			return errors.New("no api key given")
+		}
+
+		pb := pushbullet.New(key)
+		devs, err := pb.Devices()
+		if err != nil {
+			return err
+		}
+		subs, err := pb.Subscriptions()
+		if err != nil {
+			return err
+		}
+		chats, err := pb.Chats()
+		if err != nil {
+			return err
+		}
+
+		prof := cfg.Profiles[name]
+		if prof == nil {
+			prof = &Profile{}
+			cfg.Profiles[name] = prof
+		}
+		store := loginStore
+		if store == "" {
+			store = prof.Store
+		}
+		if store == "" {
+			store = storePlain
+		}
+		if prof.Store != "" && prof.Store != store {
+			if err := deleteKey(name, prof); err != nil {
+				return err
+			}
+		}
+		if err := saveKey(name, prof, store, key); err != nil {
+			return err
+		}
+
+		prof.Devices = make([]Device, 0)
+		for _, dev := range pushbullet.FilterDevices(devs, pushbullet.ActiveDevices) {
+			prof.Devices = append(prof.Devices, Device{
+				Iden: dev.Iden,
+				Name: dev.Name(),
+			})
+		}
+		prof.Channels = nil
+		for _, s := range subs {
+			if s.Active && s.Channel != nil {
+				prof.Channels = append(prof.Channels, s.Channel.Tag)
+			}
+		}
+		prof.Chats = nil
+		for _, c := range chats {
+			if c.Active && c.With != nil {
+				prof.Chats = append(prof.Chats, c.With.Email)
+			}
+		}
+		if cfg.Default == "" {
+			cfg.Default = name
+		}
+		return writeConfig(cfg)
+	},
+}
+
+var profilesCmd = &command{
+	name:  "profiles",
+	args:  "[list | use NAME | remove NAME]",
+	short: "Lists, selects or removes profiles",
+	run: func(fs *flag.FlagSet) error {
+		cfg, err := readConfig()
+		if err != nil {
+			return err
+		}
+
+		switch fs.Arg(0) {
+		case "", "list":
+			if fs.NArg() > 1 {
+				return errUsage
+			}
+			w := newTable()
+			fmt.Fprintln(w, "\tNAME\tSTORE\tDEVICES")
+			for _, name := range cfg.names() {
+				prof := cfg.Profiles[name]
+				mark := ""
+				if name == cfg.current() {
+					mark = "*"
+				}
+				store := prof.Store
+				if store == "" {
+					store = storePlain
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", mark, name, store, len(prof.Devices))
+			}
+			return w.Flush()
+		case "use":
+			if fs.NArg() != 2 {
+				return errUsage
+			}
+			name := fs.Arg(1)
+			if err := checkProfileName(name); err != nil {
+				return err
+			}
+			if cfg.Profiles[name] == nil {
+				return fmt.Errorf("no profile %q", name)
+			}
+			cfg.Default = name
+			return writeConfig(cfg)
+		case "remove":
+			if fs.NArg() != 2 {
+				return errUsage
+			}
+			name := fs.Arg(1)
+			prof := cfg.Profiles[name]
+			if prof == nil {
+				return fmt.Errorf("no profile %q", name)
+			}
+			if err := deleteKey(name, prof); err != nil {
+				return err
+			}
+			delete(cfg.Profiles, name)
+			if cfg.Default == name {
+				cfg.Default = ""
+			}
+			return writeConfig(cfg)
+		}
+		return errUsage
+	},
+}
@@ -1,190 +1,184 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
-	"runtime"
-
-	"github.com/xconstruct/go-pushbullet"
+	"strings"
 )
 
-type Config struct {
-	ApiKey  string   `json:"api_key"`
-	Devices []Device `json:"devices"`
-}
-
-type Device struct {
-	Iden string `json:"iden"`
-	Name string `json:"name"`
+// A command is a pushb subcommand.
+type command struct {
+	name  string
+	args  string
+	short string
+	flags func(fs *flag.FlagSet)
+	run   func(fs *flag.FlagSet) error
 }
 
-func getArg(i int, fallback string) string {
-	if len(os.Args) <= i {
-		return ""
+// errUsage is returned by commands that were called with invalid arguments.
+var errUsage = errors.New("invalid usage")
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		loginCmd,
+		profilesCmd,
+		noteCmd,
+		linkCmd,
+		addressCmd,
+		listCmd,
+		fileCmd,
+		smsCmd,
+		pipeCmd,
+		devicesCmd,
+		pushesCmd,
+		subscriptionsCmd,
+		chatsCmd,
+		blocksCmd,
+		watchCmd,
+		completionCmd,
 	}
-	return os.Args[i]
 }
 
+// profileName is the profile selected with the global -profile flag.
+var profileName string
+
 func main() {
-	cmd := getArg(1, "")
-
-	switch cmd {
-	case "login":
-		login()
-	case "note":
-		pushNote()
-	case "link":
-		pushLink()
-	case "devices":
-		listDevices()
-	default:
-		printHelp()
+	log.SetFlags(0)
+	log.SetPrefix("pushb: ")
+
+	gfs := flag.NewFlagSet("pushb", flag.ContinueOnError)
+	gfs.Usage = func() { printHelp("") }
+	gfs.StringVar(&profileName, "profile", os.Getenv("PUSHB_PROFILE"), "use the named profile")
+	gfs.StringVar(&profileName, "p", os.Getenv("PUSHB_PROFILE"), "shorthand for -profile")
+	if err := gfs.Parse(os.Args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		os.Exit(2)
 	}
-}
+	args := gfs.Args()
 
-func home() string {
-	home := os.Getenv("HOME")
-	if runtime.GOOS == "windows" && home == "" {
-		home = os.Getenv("USERPROFILE")
+	if len(args) < 1 {
+		printHelp("")
+		os.Exit(2)
 	}
-	return home
-}
-
-func login() {
-	key := getArg(2, "")
-	var cfg Config
-
-	cfg.ApiKey = key
-	cfg.Devices = make([]Device, 0)
-
-	if key == "" {
-		writeConfig(cfg)
+	name := args[0]
+	if name == "help" {
+		topic := ""
+		if len(args) > 1 {
+			topic = args[1]
+		}
+		printHelp(topic)
 		return
 	}
 
-	pb := pushbullet.New(key)
-	devs, err := pb.Devices()
-	if err != nil {
-		log.Fatalln(err)
-	}
-	for _, dev := range devs {
-		name := dev.Nickname
-		if name == "" {
-			name = dev.Model
-		}
-		cfg.Devices = append(cfg.Devices, Device{
-			Iden: dev.Iden,
-			Name: name,
-		})
+	if name == "__complete" {
+		complete(args[1:])
+		return
 	}
-	writeConfig(cfg)
-}
 
-func readConfig() (Config, error) {
-	cfgfile := filepath.Join(home(), ".pushb.config.json")
-	f, err := os.Open(cfgfile)
-	if err != nil {
-		return Config{}, err
+	if profileName != "" {
+		if err := checkProfileName(profileName); err != nil {
+			log.Println(err)
+			os.Exit(2)
+		}
 	}
-	defer f.Close()
-
-	var cfg Config
-	dec := json.NewDecoder(f)
-	if err = dec.Decode(&cfg); err != nil {
-		return Config{}, err
+	cmd := findCommand(name)
+	if cmd == nil {
+		log.Printf("unknown command %q", name)
+		printHelp("")
+		os.Exit(2)
 	}
-	return cfg, nil
+	os.Exit(runCommand(cmd, args[1:]))
 }
 
-func writeConfig(cfg Config) {
-	cfgfile := filepath.Join(home(), ".pushb.config.json")
-	f, err := os.OpenFile(cfgfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		log.Fatalln(err)
-	}
-	defer f.Close()
-
-	enc := json.NewEncoder(f)
-	if err = enc.Encode(cfg); err != nil {
-		log.Fatalln(err)
+func findCommand(name string) *command {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd
+		}
 	}
+	return nil
 }
 
-func pushNote() {
-	cfg, err := readConfig()
-	if err != nil {
-		log.Fatalln(err)
+// runCommand runs cmd with args and returns the process exit code.
+func runCommand(cmd *command, args []string) int {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() { printCommandHelp(cmd, fs) }
+	if cmd.flags != nil {
+		cmd.flags(fs)
 	}
-
-	title := getArg(2, "")
-	body := getArg(3, "")
-
-	if body == "-" {
-		// read stdin
-		b, err := ioutil.ReadAll(os.Stdin)
-		if err != nil {
-			log.Fatalln(err)
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
 		}
-		body = string(b)
+		return 2
 	}
 
-	pb := pushbullet.New(cfg.ApiKey)
-	err = pb.PushNote(cfg.Devices[0].Iden, title, body)
-	if err != nil {
-		log.Fatalln(err)
+	err := cmd.run(fs)
+	if code, ok := err.(exitCode); ok {
+		return int(code)
 	}
-}
-
-func pushLink() {
-	cfg, err := readConfig()
-	if err != nil {
-		log.Fatalln(err)
-	}
-
-	title := getArg(2, "")
-	link := getArg(3, "")
-	pb := pushbullet.New(cfg.ApiKey)
-	err = pb.PushLink(cfg.Devices[0].Iden, title, link, "")
-	if err != nil {
-		log.Fatalln(err)
+	switch {
+	case err == errUsage:
+		fs.Usage()
+		return 2
+	case err != nil:
+		log.Println(err)
+		return 1
 	}
+	return 0
 }
 
-func listDevices() {
-	cfg, err := readConfig()
-	if err != nil {
-		log.Fatalln(err)
+func printHelp(topic string) {
+	if topic != "" {
+		cmd := findCommand(topic)
+		if cmd == nil {
+			log.Printf("unknown help topic %q", topic)
+			os.Exit(2)
+		}
+		fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+		if cmd.flags != nil {
+			cmd.flags(fs)
+		}
+		printCommandHelp(cmd, fs)
+		return
 	}
 
-	for _, d := range cfg.Devices {
-		fmt.Printf("%10s\t%s\n", d.Iden, d.Name)
+	var b strings.Builder
+	for _, cmd := range commands {
+		fmt.Fprintf(&b, "    %-14s %s\n", cmd.name, cmd.short)
 	}
-}
-
-func printHelp() {
-	topic := getArg(2, "")
-
-	switch topic {
-	default:
-		fmt.Println(`Pushb is a simple client for PushBullet.
+	fmt.Printf(`Pushb is a simple client for PushBullet.
 
 Usage:
-    pushb command [flags] [arguments]
+    pushb [-profile NAME] command [flags] [arguments]
 
 Commands:
-    login      Saves the api key in the config
-    devices    Shows a list of registered devices
-    help       Shows this help
-
-    address    Pushes an address to a device
-    link       Pushes a link to a device
-    list       Pushes a list to a device
-    note       Pushes a note to a device
-	
-Use "pushb help [topic] for more information about that topic.`)
+%s    help           Shows this help
+
+Use "pushb help [command]" for more information about a command.
+
+Environment:
+    PUSHBULLET_TOKEN    api key to use instead of the configured profile
+    PUSHB_PROFILE       profile to use when -profile is not given
+    PUSHB_PASSPHRASE    passphrase for api keys stored in encrypted files
+    XDG_CONFIG_HOME     directory holding pushb/config.json
+`, b.String())
+}
+
+func printCommandHelp(cmd *command, fs *flag.FlagSet) {
+	fmt.Fprintf(os.Stderr, "Usage: pushb %s [flags] %s\n\n%s.\n", cmd.name, cmd.args, cmd.short)
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		fs.SetOutput(os.Stderr)
+		fs.PrintDefaults()
 	}
 }
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xconstruct/go-pushbullet"
+)
+
+// exitCode is returned by a command to exit with the code without printing
+// an error.
+type exitCode int
+
+func (e exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(e))
+}
+
+var pipeFlags struct {
+	target targetFlags
+	title  string
+	lines  int
+	chunks int
+	file   bool
+}
+
+var pipeCmd = &command{
+	name:  "pipe",
+	args:  "[-- COMMAND [ARGS...]]",
+	short: "Runs a command, or reads standard input, and pushes the result when done",
+	flags: func(fs *flag.FlagSet) {
+		pipeFlags.target.register(fs)
+		fs.StringVar(&pipeFlags.title, "title", "", "title of the push (default: the command and its outcome)")
+		fs.IntVar(&pipeFlags.lines, "lines", 20, "number of output lines to include")
+		fs.IntVar(&pipeFlags.chunks, "chunks", 0, "push the end of the output as up to this many notes instead of the last lines")
+		fs.BoolVar(&pipeFlags.file, "file", false, "push the complete output as a file")
+	},
+	run: func(fs *flag.FlagSet) error {
+		if pipeFlags.chunks < 0 || pipeFlags.lines < 0 || (pipeFlags.chunks > 0 && pipeFlags.file) {
+			return errUsage
+		}
+		pb, prof, err := newClient()
+		if err != nil {
+			return err
+		}
+		t, err := pipeFlags.target.resolve(pb, prof)
+		if err != nil {
+			return err
+		}
+
+		out, err := ioutil.TempFile("", "pushb-pipe-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(out.Name())
+		defer out.Close()
+
+		name := "stdin"
+		start := time.Now()
+		code := 0
+		if fs.NArg() == 0 {
+			_, err = io.Copy(io.MultiWriter(os.Stdout, out), os.Stdin)
+		} else {
+			name = filepath.Base(fs.Arg(0))
+			code, err = runTee(fs.Args(), out)
+		}
+		if err != nil {
+			return err
+		}
+		elapsed := time.Since(start).Round(time.Second)
+
+		title := pipeFlags.title
+		if title == "" {
+			if code == 0 {
+				title = name + " succeeded"
+			} else {
+				title = fmt.Sprintf("%s failed (exit %d)", name, code)
+			}
+		}
+		summary := fmt.Sprintf("Exit code: %d\nDuration: %s\n", code, elapsed)
+
+		switch {
+		case pipeFlags.file:
+			err = pushOutputFile(pb, t, out, name, title, summary)
+		case pipeFlags.chunks > 0:
+			err = pushOutputChunks(pb, t, out, title, summary)
+		default:
+			var tail string
+			if tail, err = tailLines(out, pipeFlags.lines, pushbullet.MaxBodyLength-len(summary)-1); err == nil {
+				_, err = pb.Push("/pushes", note(t, title, summary+"\n"+tail))
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return exitCode(code)
+		}
+		return nil
+	},
+}
+
+func note(t pushbullet.Target, title, body string) pushbullet.Note {
+	return pushbullet.Note{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "note",
+		Title: title,
+		Body:  strings.TrimRight(body, "\n"),
+	}
+}
+
+// runTee runs args with their output copied to out and returns the exit
+// code. Standard input is passed through. Interrupts are left to the command,
+// so that its outcome is still pushed.
+func runTee(args []string, out io.Writer) (int, error) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	w := &syncWriter{w: out}
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = io.MultiWriter(os.Stdout, w)
+	cmd.Stderr = io.MultiWriter(os.Stderr, w)
+	err := cmd.Run()
+	if exit, ok := err.(*exec.ExitError); ok {
+		if code := exit.ExitCode(); code >= 0 {
+			return code, nil
+		}
+		return 1, nil // killed by a signal
+	}
+	return 0, err
+}
+
+// A syncWriter serializes writes of stdout and stderr into one file.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
+// tailLines returns at most the last n lines and max bytes of f.
+func tailLines(f *os.File, n, max int) (string, error) {
+	b, err := readEnd(f, int64(max))
+	if err != nil {
+		return "", err
+	}
+	s := strings.TrimRight(string(b), "\n")
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// readEnd returns the last max bytes of f, starting at a rune boundary.
+func readEnd(f *os.File, max int64) ([]byte, error) {
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	off := size - max
+	if off < 0 {
+		off = 0
+	}
+	b := make([]byte, size-off)
+	if _, err := f.ReadAt(b, off); err != nil && err != io.EOF {
+		return nil, err
+	}
+	for len(b) > 0 && !utf8.RuneStart(b[0]) {
+		b = b[1:]
+	}
+	return b, nil
+}
+
+// pushOutputChunks pushes the end of the output as numbered notes.
+func pushOutputChunks(pb *pushbullet.Client, t pushbullet.Target, f *os.File, title, summary string) error {
+	size := pushbullet.MaxBodyLength - 100
+	b, err := readEnd(f, int64(size*pipeFlags.chunks))
+	if err != nil {
+		return err
+	}
+	chunks := splitChunks(string(b), size)
+	if len(chunks) == 0 {
+		chunks = []string{""}
+	}
+	chunks[0] = summary + "\n" + chunks[0]
+	for i, c := range chunks {
+		title := title
+		if len(chunks) > 1 {
+			title = fmt.Sprintf("%s (%d/%d)", title, i+1, len(chunks))
+		}
+		if _, err := pb.Push("/pushes", note(t, title, c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitChunks splits s into pieces of at most size bytes, preferably at line
+// breaks and never inside a rune. A rune longer than size is a piece of its
+// own.
+func splitChunks(s string, size int) []string {
+	var chunks []string
+	for len(s) > size {
+		cut := strings.LastIndexByte(s[:size], '\n') + 1
+		if cut == 0 {
+			cut = size
+			for cut > 0 && !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(s)
+			}
+		}
+		chunks = append(chunks, s[:cut])
+		s = s[cut:]
+	}
+	if s != "" {
+		chunks = append(chunks, s)
+	}
+	return chunks
+}
+
+// pushOutputFile uploads the complete output and pushes it as a file.
+func pushOutputFile(pb *pushbullet.Client, t pushbullet.Target, f *os.File, name, title, summary string) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	up, err := pb.UploadFile(name+"-output.txt", "text/plain", f)
+	if err != nil {
+		return err
+	}
+	_, err = pb.Push("/pushes", pushbullet.File{
+		Iden:     t.Device,
+		Tag:      t.Channel,
+		Email:    t.Email,
+		Type:     "file",
+		FileName: up.FileName,
+		FileType: up.FileType,
+		FileURL:  up.FileURL,
+		Body:     title + "\n" + strings.TrimRight(summary, "\n"),
+	})
+	return err
+}
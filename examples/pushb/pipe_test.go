@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitChunks(t *testing.T) {
+	cases := []struct {
+		s    string
+		size int
+		want []string
+	}{
+		{"", 10, nil},
+		{"short", 10, []string{"short"}},
+		{"exactly10!", 10, []string{"exactly10!"}},
+		{"ab\ncd\nef", 6, []string{"ab\ncd\n", "ef"}},
+		{"ab\ncdefgh", 6, []string{"ab\n", "cdefgh"}},
+		{"abcdefgh", 3, []string{"abc", "def", "gh"}},
+		// Never cut inside a rune, even when it is longer than size.
+		{"aé", 2, []string{"a", "é"}},
+		{"ééé", 3, []string{"é", "é", "é"}},
+		{"€", 1, []string{"€"}},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, splitChunks(c.s, c.size), "%q in %d", c.s, c.size)
+	}
+}
+
+func TestTailLines(t *testing.T) {
+	f, err := ioutil.TempFile("", "pushb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	cases := []struct {
+		content string
+		n, max  int
+		want    string
+	}{
+		{"", 5, 100, ""},
+		{"one\ntwo\nthree\n", 5, 100, "one\ntwo\nthree"},
+		{"one\ntwo\nthree\n\n", 2, 100, "two\nthree"},
+		{"one\ntwo\nthree", 1, 100, "three"},
+		{"one\ntwo\nthree", 0, 100, ""},
+		{"one\ntwo\nthree", 5, 7, "o\nthree"},
+		// The byte limit must not leave half a rune at the start.
+		{"héllo", 5, 4, "llo"},
+	}
+	for _, c := range cases {
+		assert.NoError(t, f.Truncate(0))
+		_, err := f.WriteAt([]byte(c.content), 0)
+		assert.NoError(t, err)
+		got, err := tailLines(f, c.n, c.max)
+		assert.NoError(t, err)
+		assert.Equal(t, c.want, got, "%q, %d lines, %d bytes", c.content, c.n, c.max)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xconstruct/go-pushbullet"
+)
+
+// targetFlags selects the receiver of a push.
+type targetFlags struct {
+	device, channel, email string
+}
+
+func (t *targetFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&t.device, "device", "", "push to the device with this name, model or iden")
+	fs.StringVar(&t.device, "d", "", "shorthand for -device")
+	fs.StringVar(&t.channel, "channel", "", "push to the channel with this tag")
+	fs.StringVar(&t.channel, "c", "", "shorthand for -channel")
+	fs.StringVar(&t.email, "email", "", "push to the user with this email address")
+	fs.StringVar(&t.email, "e", "", "shorthand for -email")
+}
+
+// resolve returns the selected target. Without a selection pushes go to all
+// devices.
+func (t *targetFlags) resolve(pb *pushbullet.Client, prof *Profile) (pushbullet.Target, error) {
+	n := 0
+	for _, v := range []string{t.device, t.channel, t.email} {
+		if v != "" {
+			n++
+		}
+	}
+	if n > 1 {
+		return pushbullet.Target{}, errors.New("only one of -device, -channel and -email may be given")
+	}
+	if t.device == "" {
+		return pushbullet.Target{Channel: t.channel, Email: t.email}, nil
+	}
+
+	iden, err := resolveDevice(pb, prof, t.device)
+	return pushbullet.Target{Device: iden}, err
+}
+
+// resolveDevice looks up a device first in the cached device list and then
+// on the server.
+func resolveDevice(pb *pushbullet.Client, prof *Profile, query string) (string, error) {
+	cached := make([]*pushbullet.Device, len(prof.Devices))
+	for i, d := range prof.Devices {
+		cached[i] = &pushbullet.Device{Iden: d.Iden, Nickname: d.Name}
+	}
+	dev, err := pushbullet.FindDevice(cached, query)
+	if err == pushbullet.ErrDeviceNotFound {
+		dev, err = pb.ResolveDevice(query)
+	}
+	if err != nil {
+		return "", err
+	}
+	return dev.Iden, nil
+}
+
+// printIden prints the iden of a created push, so that it can be dismissed
+// or deleted later.
+func printIden(p *pushbullet.Push, err error) error {
+	if err != nil {
+		return err
+	}
+	fmt.Println(p.Iden)
+	return nil
+}
+
+// readBody returns body, or standard input if body is "-".
+func readBody(body string) (string, error) {
+	if body != "-" {
+		return body, nil
+	}
+	b, err := ioutil.ReadAll(os.Stdin)
+	return string(b), err
+}
+
+var noteTarget targetFlags
+
+var noteCmd = &command{
+	name:  "note",
+	args:  "TITLE [BODY|-]",
+	short: "Pushes a note",
+	flags: noteTarget.register,
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() < 1 || fs.NArg() > 2 {
+			return errUsage
+		}
+		pb, prof, err := newClient()
+		if err != nil {
+			return err
+		}
+		t, err := noteTarget.resolve(pb, prof)
+		if err != nil {
+			return err
+		}
+		body, err := readBody(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		return printIden(pb.Push("/pushes", pushbullet.Note{
+			Iden:  t.Device,
+			Tag:   t.Channel,
+			Email: t.Email,
+			Type:  "note",
+			Title: fs.Arg(0),
+			Body:  body,
+		}))
+	},
+}
+
+var linkTarget targetFlags
+
+var linkCmd = &command{
+	name:  "link",
+	args:  "TITLE URL [BODY|-]",
+	short: "Pushes a link",
+	flags: linkTarget.register,
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() < 2 || fs.NArg() > 3 {
+			return errUsage
+		}
+		pb, prof, err := newClient()
+		if err != nil {
+			return err
+		}
+		t, err := linkTarget.resolve(pb, prof)
+		if err != nil {
+			return err
+		}
+		body, err := readBody(fs.Arg(2))
+		if err != nil {
+			return err
+		}
+		return printIden(pb.Push("/pushes", pushbullet.Link{
+			Iden:  t.Device,
+			Tag:   t.Channel,
+			Email: t.Email,
+			Type:  "link",
+			Title: fs.Arg(0),
+			URL:   fs.Arg(1),
+			Body:  body,
+		}))
+	},
+}
+
+// errEmailTarget is returned for pushes that cannot be sent to an email.
+var errEmailTarget = errors.New("address and list pushes can only be sent to devices or channels")
+
+var addressTarget targetFlags
+
+var addressCmd = &command{
+	name:  "address",
+	args:  "NAME ADDRESS",
+	short: "Pushes an address",
+	flags: addressTarget.register,
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() != 2 {
+			return errUsage
+		}
+		pb, prof, err := newClient()
+		if err != nil {
+			return err
+		}
+		t, err := addressTarget.resolve(pb, prof)
+		if err != nil {
+			return err
+		}
+		switch {
+		case t.Email != "":
+			return errEmailTarget
+		case t.Channel != "":
+			return printIden(pb.PushAddressToChannel(t.Channel, fs.Arg(0), fs.Arg(1)))
+		}
+		return printIden(pb.PushAddress(t.Device, fs.Arg(0), fs.Arg(1)))
+	},
+}
+
+var listTarget targetFlags
+
+var listCmd = &command{
+	name:  "list",
+	args:  "TITLE ITEM...",
+	short: "Pushes a checklist; items starting with \"[x] \" are checked",
+	flags: listTarget.register,
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() < 2 {
+			return errUsage
+		}
+		pb, prof, err := newClient()
+		if err != nil {
+			return err
+		}
+		t, err := listTarget.resolve(pb, prof)
+		if err != nil {
+			return err
+		}
+		var items []pushbullet.ListItem
+		for _, arg := range fs.Args()[1:] {
+			item := pushbullet.ListItem{Text: strings.TrimPrefix(arg, "[ ] ")}
+			if strings.HasPrefix(arg, "[x] ") {
+				item = pushbullet.ListItem{Text: arg[4:], Checked: true}
+			}
+			items = append(items, item)
+		}
+		switch {
+		case t.Email != "":
+			return errEmailTarget
+		case t.Channel != "":
+			return printIden(pb.PushListToChannel(t.Channel, fs.Arg(0), items))
+		}
+		return printIden(pb.PushList(t.Device, fs.Arg(0), items))
+	},
+}
+
+var fileTarget targetFlags
+
+var fileCmd = &command{
+	name:  "file",
+	args:  "PATH [BODY|-]",
+	short: "Uploads and pushes a file",
+	flags: fileTarget.register,
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() < 1 || fs.NArg() > 2 {
+			return errUsage
+		}
+		pb, prof, err := newClient()
+		if err != nil {
+			return err
+		}
+		t, err := fileTarget.resolve(pb, prof)
+		if err != nil {
+			return err
+		}
+		body, err := readBody(fs.Arg(1))
+		if err != nil {
+			return err
+		}
+
+		path := fs.Arg(0)
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fileType, err := detectType(f)
+		if err != nil {
+			return err
+		}
+		up, err := pb.UploadFile(filepath.Base(path), fileType, f)
+		if err != nil {
+			return err
+		}
+		return printIden(pb.Push("/pushes", pushbullet.File{
+			Iden:     t.Device,
+			Tag:      t.Channel,
+			Email:    t.Email,
+			Type:     "file",
+			FileName: up.FileName,
+			FileType: up.FileType,
+			FileURL:  up.FileURL,
+			Body:     body,
+		}))
+	},
+}
+
+// detectType guesses the MIME type of f from its name or contents and
+// rewinds it.
+func detectType(f *os.File) (string, error) {
+	if t := mime.TypeByExtension(filepath.Ext(f.Name())); t != "" {
+		return t, nil
+	}
+	var head [512]byte
+	n, _ := f.Read(head[:])
+	if _, err := f.Seek(0, 0); err != nil {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+var smsDevice string
+
+var smsCmd = &command{
+	name:  "sms",
+	args:  "-device DEVICE NUMBER MESSAGE",
+	short: "Sends an SMS through a phone",
+	flags: func(fs *flag.FlagSet) {
+		fs.StringVar(&smsDevice, "device", "", "send from the phone with this name, model or iden")
+		fs.StringVar(&smsDevice, "d", "", "shorthand for -device")
+	},
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() != 2 || smsDevice == "" {
+			return errUsage
+		}
+		pb, prof, err := newClient()
+		if err != nil {
+			return err
+		}
+		iden, err := resolveDevice(pb, prof, smsDevice)
+		if err != nil {
+			return err
+		}
+		user, err := pb.Me()
+		if err != nil {
+			return err
+		}
+		return pb.PushSMS(user.Iden, iden, fs.Arg(0), fs.Arg(1))
+	},
+}
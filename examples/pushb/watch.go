@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/xconstruct/go-pushbullet"
+)
+
+// An event is something pushb watch reports.
+type event struct {
+	Event     string                      `json:"event"` // "push", "ephemeral" or "sms"
+	Type      string                      `json:"type"`
+	Iden      string                      `json:"iden,omitempty"`
+	Title     string                      `json:"title,omitempty"`
+	Body      string                      `json:"body,omitempty"`
+	URL       string                      `json:"url,omitempty"`
+	Sender    string                      `json:"sender,omitempty"`
+	Device    string                      `json:"device,omitempty"`
+	Push      *pushbullet.Push            `json:"push,omitempty"`
+	Ephemeral *pushbullet.StreamEphemeral `json:"ephemeral,omitempty"`
+	SMS       *pushbullet.SMSNotification `json:"sms,omitempty"`
+}
+
+var watchFlags struct {
+	json bool
+	exec string
+}
+
+var watchCmd = &command{
+	name:  "watch",
+	short: "Prints pushes, ephemerals and SMS as they arrive",
+	flags: func(fs *flag.FlagSet) {
+		fs.BoolVar(&watchFlags.json, "json", false, "print one JSON object per event")
+		fs.StringVar(&watchFlags.exec, "exec", "", "run this shell command for every event, with the event in PUSHB_* environment variables")
+	},
+	run: func(fs *flag.FlagSet) error {
+		if fs.NArg() != 0 {
+			return errUsage
+		}
+		pb, _, err := newClient()
+		if err != nil {
+			return err
+		}
+		return watch(pb)
+	},
+}
+
+// watch streams events until interrupted, reconnecting when the connection
+// is lost. It gives up if the api key is rejected.
+func watch(pb *pushbullet.Client) error {
+	if err := checkKey(pb); err != nil {
+		return err
+	}
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	since := time.Now()
+	seen := map[string]bool{}
+	backoff := time.Second
+	for {
+		s, err := pb.Stream()
+		if err != nil {
+			// Failed handshakes do not tell a revoked key from an outage.
+			if err := checkKey(pb); err != nil {
+				return err
+			}
+			log.Printf("connecting to stream: %v (retrying in %s)", err, backoff)
+			select {
+			case <-interrupt:
+				return nil
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > time.Minute {
+				backoff = time.Minute
+			}
+			continue
+		}
+		backoff = time.Second
+
+		// Only this loop closes stop; the goroutine reports whether the
+		// stream ended because of an interrupt.
+		stop := make(chan struct{})
+		interrupted := make(chan bool, 1)
+		go func() {
+			select {
+			case <-interrupt:
+				s.Close()
+				interrupted <- true
+			case <-stop:
+				interrupted <- false
+			}
+		}()
+
+		for {
+			var m *pushbullet.StreamMessage
+			m, err = s.Next()
+			if err != nil {
+				break
+			}
+			switch {
+			case m.Type == "tickle" && m.Subtype == "push":
+				since, err = reportPushes(pb, since, seen)
+			case m.Type == "push":
+				err = reportEphemeral(m)
+			}
+			if err != nil {
+				log.Println(err)
+			}
+		}
+		s.Close()
+		close(stop)
+		if <-interrupted {
+			return nil
+		}
+		log.Printf("stream: %v (reconnecting)", err)
+	}
+}
+
+// checkKey returns an error if PushBullet rejects the api key of pb. Errors
+// that may go away, such as being offline, are ignored.
+func checkKey(pb *pushbullet.Client) error {
+	_, err := pb.Me()
+	if e, ok := err.(*pushbullet.ErrResponse); ok && !e.Temporary() {
+		return err
+	}
+	return nil
+}
+
+// reportPushes reports the pushes created since the last check and returns
+// the new modification time to check from.
+func reportPushes(pb *pushbullet.Client, since time.Time, seen map[string]bool) (time.Time, error) {
+	pushes, err := pb.PushesSince(since)
+	if err != nil {
+		return since, err
+	}
+	for i := len(pushes) - 1; i >= 0; i-- {
+		p := pushes[i]
+		if p.Modified.After(since) {
+			since = p.Modified.Time
+		}
+		if !p.Active || p.Dismissed || seen[p.Iden] {
+			continue
+		}
+		seen[p.Iden] = true
+		sender := p.SenderName
+		if sender == "" {
+			sender = p.SenderEmail
+		}
+		body := p.Body
+		if p.FileURL != "" {
+			body = strings.TrimSpace(body + "\n" + p.FileURL)
+		}
+		report(&event{
+			Event:  "push",
+			Type:   p.Type,
+			Iden:   p.Iden,
+			Title:  p.Title,
+			Body:   body,
+			URL:    p.URL,
+			Sender: sender,
+			Device: p.SourceDeviceIden,
+			Push:   p,
+		})
+	}
+	return since, nil
+}
+
+func reportEphemeral(m *pushbullet.StreamMessage) error {
+	eph, err := m.Ephemeral()
+	if err != nil {
+		return err
+	}
+	if eph.Type == "sms_changed" {
+		for _, n := range eph.Notifications {
+			report(&event{
+				Event:  "sms",
+				Type:   eph.Type,
+				Iden:   n.ThreadID,
+				Title:  n.Title,
+				Body:   n.Body,
+				Sender: n.Title,
+				Device: eph.SourceDeviceIden,
+				SMS:    n,
+			})
+		}
+		return nil
+	}
+	report(&event{
+		Event:     "ephemeral",
+		Type:      eph.Type,
+		Iden:      eph.NotificationID,
+		Title:     eph.Title,
+		Body:      eph.Body,
+		Sender:    eph.ApplicationName,
+		Device:    eph.SourceDeviceIden,
+		Ephemeral: eph,
+	})
+	return nil
+}
+
+func report(e *event) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if watchFlags.json {
+		fmt.Println(string(b))
+	} else {
+		line := fmt.Sprintf("%s [%s %s]", time.Now().Format("15:04:05"), e.Event, e.Type)
+		if e.Sender != "" {
+			line += " " + e.Sender + ":"
+		}
+		for _, s := range []string{e.Title, e.Body, e.URL} {
+			if s != "" {
+				line += " " + strings.Replace(s, "\n", " ", -1)
+			}
+		}
+		fmt.Println(line)
+	}
+
+	if watchFlags.exec != "" {
+		runHook(watchFlags.exec, e, b)
+	}
+}
+
+// runHook runs the shell command cmdline with the event in its environment.
+func runHook(cmdline string, e *event, b []byte) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", cmdline)
+	} else {
+		cmd = exec.Command("/bin/sh", "-c", cmdline)
+	}
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"PUSHB_EVENT="+e.Event,
+		"PUSHB_TYPE="+e.Type,
+		"PUSHB_IDEN="+e.Iden,
+		"PUSHB_TITLE="+e.Title,
+		"PUSHB_BODY="+e.Body,
+		"PUSHB_URL="+e.URL,
+		"PUSHB_SENDER="+e.Sender,
+		"PUSHB_DEVICE="+e.Device,
+		"PUSHB_JSON="+string(b),
+	)
+	if err := cmd.Run(); err != nil {
+		log.Printf("exec: %v", err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xconstruct/go-pushbullet"
+)
+
+func TestCheckKey(t *testing.T) {
+	for status, fatal := range map[int]bool{
+		http.StatusOK:                 false,
+		http.StatusUnauthorized:       true,
+		http.StatusForbidden:          true,
+		http.StatusServiceUnavailable: false,
+	} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error": {"type": "invalid_request", "message": "Access token is missing or invalid."}}`))
+		}))
+		pb := pushbullet.New("API_KEY")
+		pb.Retries = 0
+		pb.Endpoint.URL = server.URL
+		err := checkKey(pb)
+		assert.Equal(t, fatal, err != nil, "%d: %v", status, err)
+		server.Close()
+	}
+}
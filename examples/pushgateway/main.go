@@ -0,0 +1,68 @@
+// Pushgateway serves the webhook-to-push gateway of package gateway.
+//
+// Usage:
+//
+//	pushgateway -config pushgateway.json [-listen :8080]
+//
+// The config file looks like:
+//
+//	{
+//	  "api_key": "YOUR_API_KEY",
+//	  "tokens": ["SECRET"],
+//	  "hooks": {
+//	    "grafana": {
+//	      "type": "link",
+//	      "title": "{{.title}}",
+//	      "body": "{{.message}}",
+//	      "url": "{{.ruleUrl}}",
+//	      "channel": "ops"
+//	    }
+//	  }
+//	}
+//
+// The api key may also be given in the PUSHBULLET_TOKEN environment variable.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/xconstruct/go-pushbullet"
+	"github.com/xconstruct/go-pushbullet/gateway"
+)
+
+type Config struct {
+	ApiKey string                  `json:"api_key"`
+	Tokens []string                `json:"tokens"`
+	Hooks  map[string]gateway.Hook `json:"hooks"`
+}
+
+func main() {
+	listen := flag.String("listen", ":8080", "address to listen on")
+	cfgfile := flag.String("config", "pushgateway.json", "path of the config file")
+	flag.Parse()
+
+	f, err := os.Open(*cfgfile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	var cfg Config
+	err = json.NewDecoder(f).Decode(&cfg)
+	f.Close()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if key := os.Getenv("PUSHBULLET_TOKEN"); key != "" {
+		cfg.ApiKey = key
+	}
+
+	g, err := gateway.New(pushbullet.New(cfg.ApiKey), cfg.Tokens, cfg.Hooks)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("listening on", *listen)
+	log.Fatalln(http.ListenAndServe(*listen, g))
+}
@@ -0,0 +1,230 @@
+package pushbullet
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Target is the receiver of a push. Exactly one of its fields should be set.
+type Target struct {
+	Device  string // device iden
+	Channel string // channel tag
+	Email   string
+}
+
+// DeviceTarget returns a Target for the device with the given iden.
+func DeviceTarget(iden string) Target {
+	return Target{Device: iden}
+}
+
+// ChannelTarget returns a Target for the channel with the given tag.
+func ChannelTarget(tag string) Target {
+	return Target{Channel: tag}
+}
+
+// EmailTarget returns a Target for the user with the given email address.
+func EmailTarget(email string) Target {
+	return Target{Email: email}
+}
+
+func (t Target) String() string {
+	switch {
+	case t.Device != "":
+		return "device " + t.Device
+	case t.Channel != "":
+		return "channel " + t.Channel
+	case t.Email != "":
+		return "email " + t.Email
+	}
+	return "all devices"
+}
+
+func (t Target) note(title, body string) Note {
+	return Note{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "note",
+		Title: title,
+		Body:  body,
+	}
+}
+
+func (t Target) link(title, u, body string) Link {
+	return Link{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "link",
+		Title: title,
+		URL:   u,
+		Body:  body,
+	}
+}
+
+// PushResult is the outcome of pushing to a single Target. Push is the
+// created push and Iden its iden; both are only set if Err is nil.
+type PushResult struct {
+	Target Target
+	Iden   string
+	Push   *Push
+	Err    error
+}
+
+// MultiPushError is returned by a fan-out push when at least one target
+// failed. Results holds the outcome for every target, in the order the
+// targets were given.
+type MultiPushError struct {
+	Results []PushResult
+}
+
+// Failed returns the results of the targets that could not be pushed to.
+func (e *MultiPushError) Failed() []PushResult {
+	var failed []PushResult
+	for _, r := range e.Results {
+		if r.Err != nil {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+func (e *MultiPushError) Error() string {
+	failed := e.Failed()
+	msgs := make([]string, len(failed))
+	for i, r := range failed {
+		msgs[i] = r.Target.String() + ": " + r.Err.Error()
+	}
+	return fmt.Sprintf("%d of %d pushes failed: %s", len(failed), len(e.Results), strings.Join(msgs, "; "))
+}
+
+// A Fanout sends the same push to several targets concurrently.
+type Fanout struct {
+	Client *Client
+
+	// Workers is the maximum number of pushes in flight. Defaults to 4.
+	Workers int
+
+	// Interval is the minimum time between the start of two requests,
+	// shared by all workers. Zero means no spacing.
+	Interval time.Duration
+
+	// Retries is how often a push rejected with a RateLimitError is
+	// retried once the rate limit resets.
+	Retries int
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewFanout creates a Fanout for the client with default settings.
+func NewFanout(c *Client) *Fanout {
+	return &Fanout{Client: c, Workers: 4, Retries: 1}
+}
+
+// PushNote pushes a note with title and body to all targets.
+func (f *Fanout) PushNote(targets []Target, title, body string) ([]PushResult, error) {
+	return f.Push(targets, func(t Target) interface{} {
+		return t.note(title, body)
+	})
+}
+
+// PushLink pushes a link with title, url and body to all targets.
+func (f *Fanout) PushLink(targets []Target, title, u, body string) ([]PushResult, error) {
+	return f.Push(targets, func(t Target) interface{} {
+		return t.link(title, u, body)
+	})
+}
+
+// Push sends the payload built for each target to /pushes. It always returns
+// one result per target; the error is a *MultiPushError if any push failed.
+// Unless validation is disabled, pushing to no targets fails with
+// ErrNoTargets.
+func (f *Fanout) Push(targets []Target, build func(t Target) interface{}) ([]PushResult, error) {
+	if len(targets) == 0 && !f.Client.SkipValidation {
+		return nil, ErrNoTargets
+	}
+	results := make([]PushResult, len(targets))
+	workers := f.Workers
+	if workers <= 0 {
+		workers = 4
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(targets); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = f.pushOne(targets[i], build(targets[i]))
+			}
+		}()
+	}
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, r := range results {
+		if r.Err != nil {
+			return results, &MultiPushError{Results: results}
+		}
+	}
+	return results, nil
+}
+
+func (f *Fanout) pushOne(t Target, data interface{}) PushResult {
+	// Retries reuse the guid so that they cannot notify twice.
+	data = ensureGUID(data)
+	for attempt := 0; ; attempt++ {
+		f.wait()
+		pushed, err := f.Client.Push("/pushes", data)
+		if rl, ok := err.(*RateLimitError); ok && attempt < f.Retries {
+			f.delay(rl.Reset)
+			continue
+		}
+		if err != nil {
+			return PushResult{Target: t, Err: err}
+		}
+		return PushResult{Target: t, Iden: pushed.Iden, Push: pushed}
+	}
+}
+
+// wait blocks until the next request slot according to Interval.
+func (f *Fanout) wait() {
+	f.mu.Lock()
+	now := time.Now()
+	start := f.next
+	if start.Before(now) {
+		start = now
+	}
+	f.next = start.Add(f.Interval)
+	f.mu.Unlock()
+
+	time.Sleep(time.Until(start))
+}
+
+// delay pushes back the next request slot of all workers until t.
+func (f *Fanout) delay(t time.Time) {
+	f.mu.Lock()
+	if t.After(f.next) {
+		f.next = t
+	}
+	f.mu.Unlock()
+}
+
+// PushNoteMulti pushes a note with title and body to all targets using a
+// Fanout with default settings.
+func (c *Client) PushNoteMulti(targets []Target, title, body string) ([]PushResult, error) {
+	return NewFanout(c).PushNote(targets, title, body)
+}
+
+// PushLinkMulti pushes a link with title, url and body to all targets using a
+// Fanout with default settings.
+func (c *Client) PushLinkMulti(targets []Target, title, u, body string) ([]PushResult, error) {
+	return NewFanout(c).PushLink(targets, title, u, body)
+}
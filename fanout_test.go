@@ -0,0 +1,90 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func FanoutResponseStub(limited *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var note Note
+		json.NewDecoder(r.Body).Decode(&note)
+		switch {
+		case note.Iden == "broken":
+			e, _ := json.Marshal(e)
+			http.Error(w, `{ "error":`+string(e)+`}`, http.StatusBadRequest)
+			return
+		case note.Tag != "" && atomic.AddInt32(limited, -1) >= 0:
+			w.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"iden": "push-` + note.Iden + note.Tag + note.Email + `"}`))
+	}))
+}
+
+func TestPushNoteMulti(t *testing.T) {
+	limited := int32(1)
+	server := FanoutResponseStub(&limited)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	targets := []Target{
+		DeviceTarget("a"),
+		DeviceTarget("b"),
+		ChannelTarget("news"),
+		EmailTarget("elon@teslamotors.com"),
+	}
+	results, err := pb.PushNoteMulti(targets, n.Title, n.Body)
+	assert.NoError(t, err)
+	assert.Len(t, results, 4)
+	assert.Equal(t, "push-a", results[0].Iden)
+	assert.Equal(t, "push-a", results[0].Push.Iden)
+	assert.Equal(t, "push-news", results[2].Iden)
+	assert.Equal(t, targets[3], results[3].Target)
+}
+
+func TestPushLinkMultiError(t *testing.T) {
+	limited := int32(0)
+	server := FanoutResponseStub(&limited)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	targets := []Target{DeviceTarget("a"), DeviceTarget("broken")}
+	results, err := pb.PushLinkMulti(targets, l.Title, l.URL, l.Body)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "push-a", results[0].Iden)
+	rejected := *e
+	rejected.StatusCode = http.StatusBadRequest
+	assert.Equal(t, &rejected, results[1].Err)
+	if assert.IsType(t, &MultiPushError{}, err) {
+		assert.Len(t, err.(*MultiPushError).Failed(), 1)
+		assert.Equal(t, "1 of 2 pushes failed: device broken: "+e.Message, err.Error())
+	}
+}
+
+func TestFanoutRateLimited(t *testing.T) {
+	limited := int32(5)
+	server := FanoutResponseStub(&limited)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	f := NewFanout(pb)
+	f.Workers = 1
+	f.Retries = 2
+	results, err := f.PushNote([]Target{ChannelTarget("news")}, n.Title, n.Body)
+	assert.Error(t, err)
+	assert.IsType(t, &RateLimitError{}, results[0].Err)
+	assert.Equal(t, int32(2), limited)
+}
@@ -0,0 +1,194 @@
+package pushbullet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"os"
+)
+
+// File exposes the required and optional fields of the Pushbullet push type=file
+type File struct {
+	Iden     string `json:"device_iden,omitempty"`
+	Tag      string `json:"channel_tag,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Type     string `json:"type"`
+	GUID     string `json:"guid,omitempty"`
+	FileName string `json:"file_name"`
+	FileType string `json:"file_type"`
+	FileURL  string `json:"file_url"`
+	Body     string `json:"body,omitempty"`
+}
+
+// An Upload is a file uploaded to PushBullet's storage, ready to be pushed.
+type Upload struct {
+	FileName  string            `json:"file_name"`
+	FileType  string            `json:"file_type"`
+	FileURL   string            `json:"file_url"`
+	UploadURL string            `json:"upload_url"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// FileTooLargeError is returned by UploadFile when a file exceeds the user's
+// upload limit. Nothing is uploaded in that case.
+type FileTooLargeError struct {
+	Size  int64 // size of the file, or the bytes read so far if not known
+	Limit int64 // the user's max_upload_size
+}
+
+func (e *FileTooLargeError) Error() string {
+	return fmt.Sprintf("File too large: %d bytes exceeds the upload limit of %d bytes", e.Size, e.Limit)
+}
+
+// UploadFile uploads the contents of r as a file with the given name and MIME
+// type. The returned Upload's FileURL can then be pushed with PushFile.
+//
+// The file is checked against the user's MaxUploadSize first. If the size of
+// r can be told in advance (a *bytes.Reader, *strings.Reader, *bytes.Buffer or
+// regular *os.File), a file that is too large is rejected before anything is
+// sent; otherwise it is rejected before the file is uploaded.
+func (c *Client) UploadFile(fileName, fileType string, r io.Reader) (*Upload, error) {
+	limit, err := c.MaxUploadSize()
+	if err != nil {
+		return nil, err
+	}
+	if size, ok := readerSize(r); ok && limit > 0 && size > limit {
+		return nil, &FileTooLargeError{Size: size, Limit: limit}
+	}
+
+	req := struct {
+		FileName string `json:"file_name"`
+		FileType string `json:"file_type"`
+	}{fileName, fileType}
+	up, err := post[Upload](c, "/upload-request", req)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	for k, v := range up.Data {
+		if err := mw.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	fw, err := mw.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 {
+		n, err := io.Copy(fw, io.LimitReader(r, limit+1))
+		if err != nil {
+			return nil, err
+		}
+		if n > limit {
+			return nil, &FileTooLargeError{Size: n, Limit: limit}
+		}
+	} else if _, err = io.Copy(fw, r); err != nil {
+		return nil, err
+	}
+	if err = mw.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.Client.Post(up.UploadURL, mw.FormDataContentType(), &b)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var errjson errorResponse
+		dec := json.NewDecoder(resp.Body)
+		if dec.Decode(&errjson) == nil && errjson.Message != "" {
+			return nil, &errjson.ErrResponse
+		}
+		return nil, errors.New(resp.Status)
+	}
+	return up, nil
+}
+
+// readerSize returns the number of bytes left in r, if it can tell without
+// reading.
+func readerSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0, false
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return fi.Size() - pos, true
+	}
+	return 0, false
+}
+
+// PushFile pushes a previously uploaded file to a specific PushBullet device.
+func (c *Client) PushFile(iden, fileName, fileType, fileURL, body string) (*Push, error) {
+	data := File{
+		Iden:     iden,
+		Type:     "file",
+		FileName: fileName,
+		FileType: fileType,
+		FileURL:  fileURL,
+		Body:     body,
+	}
+	return c.Push("/pushes", data)
+}
+
+// PushFileToChannel pushes a previously uploaded file to a specific PushBullet
+// channel.
+func (c *Client) PushFileToChannel(tag, fileName, fileType, fileURL, body string) (*Push, error) {
+	data := File{
+		Tag:      tag,
+		Type:     "file",
+		FileName: fileName,
+		FileType: fileType,
+		FileURL:  fileURL,
+		Body:     body,
+	}
+	return c.Push("/pushes", data)
+}
+
+// PushFile sends a previously uploaded file to the specific device
+func (d *Device) PushFile(fileName, fileType, fileURL, body string) (*Push, error) {
+	return d.Client.PushFile(d.Iden, fileName, fileType, fileURL, body)
+}
+
+// PushFile sends a previously uploaded file to the specific Channel
+func (s *Subscription) PushFile(fileName, fileType, fileURL, body string) (*Push, error) {
+	return s.Client.PushFileToChannel(s.Channel.Tag, fileName, fileType, fileURL, body)
+}
+
+func (t Target) file(fileName, fileType, fileURL, body string) File {
+	return File{
+		Iden:     t.Device,
+		Tag:      t.Channel,
+		Email:    t.Email,
+		Type:     "file",
+		FileName: fileName,
+		FileType: fileType,
+		FileURL:  fileURL,
+		Body:     body,
+	}
+}
+
+// PushFile pushes a previously uploaded file to all targets.
+func (f *Fanout) PushFile(targets []Target, fileName, fileType, fileURL, body string) ([]PushResult, error) {
+	return f.Push(targets, func(t Target) interface{} {
+		return t.file(fileName, fileType, fileURL, body)
+	})
+}
+
+// PushFileMulti pushes a previously uploaded file to all targets using a
+// Fanout with default settings.
+func (c *Client) PushFileMulti(targets []Target, fileName, fileType, fileURL, body string) ([]PushResult, error) {
+	return NewFanout(c).PushFile(targets, fileName, fileType, fileURL, body)
+}
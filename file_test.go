@@ -0,0 +1,114 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func FileResponseStub(uploaded *string, pushed *File) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/me":
+			w.Write([]byte(`{"iden": "ujpah72o0", "max_upload_size": 10}`))
+		case "/upload-request":
+			var req Upload
+			json.NewDecoder(r.Body).Decode(&req)
+			json.NewEncoder(w).Encode(Upload{
+				FileName:  req.FileName,
+				FileType:  req.FileType,
+				FileURL:   "https://dl.pushbulletusercontent.com/abc/" + req.FileName,
+				UploadURL: server.URL + "/upload",
+			})
+		case "/upload":
+			if r.Header.Get("Authorization") != "" {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			b, _ := ioutil.ReadAll(f)
+			*uploaded = string(b)
+			w.WriteHeader(http.StatusNoContent)
+		case "/pushes":
+			json.NewDecoder(r.Body).Decode(pushed)
+			w.Write([]byte(`{"iden": "file-push"}`))
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestUploadAndPushFile(t *testing.T) {
+	var uploaded string
+	var pushed File
+	server := FileResponseStub(&uploaded, &pushed)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	up, err := pb.UploadFile("notes.txt", "text/plain", strings.NewReader("hello"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", uploaded)
+	assert.Equal(t, "https://dl.pushbulletusercontent.com/abc/notes.txt", up.FileURL)
+
+	push, err := pb.PushFile(d.Iden, up.FileName, up.FileType, up.FileURL, "see attached")
+	assert.NoError(t, err)
+	assert.Equal(t, "file-push", push.Iden)
+	assert.NotEmpty(t, pushed.GUID)
+	pushed.GUID = ""
+	assert.Equal(t, File{
+		Iden:     d.Iden,
+		Type:     "file",
+		FileName: "notes.txt",
+		FileType: "text/plain",
+		FileURL:  up.FileURL,
+		Body:     "see attached",
+	}, pushed)
+
+	results, err := pb.PushFileMulti([]Target{ChannelTarget("ops")}, up.FileName, up.FileType, up.FileURL, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "file-push", results[0].Iden)
+	assert.Equal(t, "ops", pushed.Tag)
+}
+
+func TestUploadFileError(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	_, err := pb.UploadFile("notes.txt", "text/plain", strings.NewReader("hello"))
+	assert.Equal(t, e, err)
+}
+
+func TestUploadFileTooLarge(t *testing.T) {
+	var uploaded string
+	var pushed File
+	server := FileResponseStub(&uploaded, &pushed)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	// The size of a strings.Reader is known before uploading.
+	_, err := pb.UploadFile("big.txt", "text/plain", strings.NewReader("hello world"))
+	assert.Equal(t, &FileTooLargeError{Size: 11, Limit: 10}, err)
+
+	// Other readers are cut off once they exceed the limit.
+	_, err = pb.UploadFile("big.txt", "text/plain", ioutil.NopCloser(strings.NewReader("hello world!")))
+	assert.Equal(t, &FileTooLargeError{Size: 11, Limit: 10}, err)
+	assert.Equal(t, "", uploaded)
+
+	_, err = pb.UploadFile("ok.txt", "text/plain", ioutil.NopCloser(strings.NewReader("just fits!")))
+	assert.NoError(t, err)
+	assert.Equal(t, "just fits!", uploaded)
+}
@@ -0,0 +1,374 @@
+// Package gateway provides an HTTP server that turns webhook calls into
+// Pushbullet pushes, for tools that can only call a URL.
+/*
+
+Endpoints:
+	POST /note             title, body
+	POST /link             title, url, body
+	POST /file             multipart form with a "file" field, body
+	POST /hooks/{name}     any JSON or form payload, rendered by a Hook
+
+All endpoints accept JSON or form bodies and the target fields device,
+channel and email; without a target the push goes to all devices. Requests
+must carry one of the gateway's tokens as "Authorization: Bearer TOKEN" or in
+the "token" query parameter. The response is {"iden": "..."} with the iden of
+the created push.
+
+*/
+package gateway
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/xconstruct/go-pushbullet"
+	"github.com/xconstruct/go-pushbullet/render"
+)
+
+// maxBodySize limits the size of request bodies other than file uploads.
+const maxBodySize = 1 << 20
+
+// A Hook maps arbitrary webhook payloads to a push. Every field is a
+// text/template executed with the decoded JSON or form payload as data; the
+// helper functions of package render are available. A payload that lacks a
+// key a template refers to is answered with 400 Bad Request; use index for
+// optional keys, as in {{default "none" (index . "message")}}.
+type Hook struct {
+	Type    string `json:"type"` // "note" or "link"; defaults to "note"
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	URL     string `json:"url"`
+	Device  string `json:"device"`
+	Channel string `json:"channel"`
+	Email   string `json:"email"`
+}
+
+type hook struct {
+	typ                                      string
+	title, body, url, device, channel, email *template.Template
+}
+
+// A Gateway is an http.Handler creating pushes with Client.
+type Gateway struct {
+	Client *pushbullet.Client
+
+	// MaxFileSize limits the size of uploaded files. Defaults to 25 MB.
+	MaxFileSize int64
+
+	tokens []string
+	hooks  map[string]*hook
+	mux    *http.ServeMux
+}
+
+// New creates a Gateway that accepts requests authenticated with one of
+// tokens and serves the given hooks. All hook templates are parsed up front;
+// an invalid one is reported as an error.
+func New(c *pushbullet.Client, tokens []string, hooks map[string]Hook) (*Gateway, error) {
+	if len(tokens) == 0 {
+		return nil, errors.New("gateway: at least one token is required")
+	}
+
+	g := &Gateway{
+		Client:      c,
+		MaxFileSize: 25 << 20,
+		tokens:      tokens,
+		hooks:       make(map[string]*hook),
+		mux:         http.NewServeMux(),
+	}
+	for name, h := range hooks {
+		parsed, err := parseHook(name, h)
+		if err != nil {
+			return nil, err
+		}
+		g.hooks[name] = parsed
+	}
+
+	g.mux.HandleFunc("/note", g.handleNote)
+	g.mux.HandleFunc("/link", g.handleLink)
+	g.mux.HandleFunc("/file", g.handleFile)
+	g.mux.HandleFunc("/hooks/", g.handleHook)
+	return g, nil
+}
+
+func parseHook(name string, h Hook) (*hook, error) {
+	parsed := &hook{typ: h.Type}
+	switch h.Type {
+	case "":
+		parsed.typ = "note"
+	case "note", "link":
+	default:
+		return nil, errors.New("gateway: hook " + name + ": unknown type " + h.Type)
+	}
+	if parsed.typ == "link" && h.URL == "" {
+		return nil, errors.New("gateway: hook " + name + ": link hooks need a url template")
+	}
+
+	fields := []struct {
+		dst  **template.Template
+		name string
+		text string
+	}{
+		{&parsed.title, "title", h.Title},
+		{&parsed.body, "body", h.Body},
+		{&parsed.url, "url", h.URL},
+		{&parsed.device, "device", h.Device},
+		{&parsed.channel, "channel", h.Channel},
+		{&parsed.email, "email", h.Email},
+	}
+	for _, f := range fields {
+		t, err := template.New(name + "." + f.name).Funcs(render.Funcs()).Option("missingkey=error").Parse(f.text)
+		if err != nil {
+			return nil, errors.New("gateway: hook " + name + ": " + err.Error())
+		}
+		*f.dst = t
+	}
+	return parsed, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !g.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="pushbullet-gateway"`)
+		writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+		return
+	}
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *Gateway) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if token == "" {
+		return false
+	}
+	for _, t := range g.tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gateway) handleNote(w http.ResponseWriter, r *http.Request) {
+	fields, err := readFields(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	t := targetOf(fields)
+	g.push(w, pushbullet.Note{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "note",
+		Title: fields["title"],
+		Body:  fields["body"],
+	})
+}
+
+func (g *Gateway) handleLink(w http.ResponseWriter, r *http.Request) {
+	fields, err := readFields(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if fields["url"] == "" {
+		writeError(w, http.StatusBadRequest, errors.New("url is required"))
+		return
+	}
+	t := targetOf(fields)
+	g.push(w, pushbullet.Link{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "link",
+		Title: fields["title"],
+		URL:   fields["url"],
+		Body:  fields["body"],
+	})
+}
+
+func (g *Gateway) handleFile(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, g.MaxFileSize)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	f, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer f.Close()
+
+	fileType := header.Header.Get("Content-Type")
+	if fileType == "" {
+		fileType = "application/octet-stream"
+	}
+	up, err := g.Client.UploadFile(header.Filename, fileType, f)
+	if _, tooLarge := err.(*pushbullet.FileTooLargeError); tooLarge {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	fields := map[string]string{}
+	for k := range r.MultipartForm.Value {
+		fields[k] = r.FormValue(k)
+	}
+	t := targetOf(fields)
+	g.push(w, pushbullet.File{
+		Iden:     t.Device,
+		Tag:      t.Channel,
+		Email:    t.Email,
+		Type:     "file",
+		FileName: up.FileName,
+		FileType: up.FileType,
+		FileURL:  up.FileURL,
+		Body:     fields["body"],
+	})
+}
+
+func (g *Gateway) handleHook(w http.ResponseWriter, r *http.Request) {
+	h, ok := g.hooks[strings.TrimPrefix(r.URL.Path, "/hooks/")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errors.New("unknown hook"))
+		return
+	}
+	data, err := readData(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rendered := map[string]string{}
+	for name, t := range map[string]*template.Template{
+		"title": h.title, "body": h.body, "url": h.url,
+		"device": h.device, "channel": h.channel, "email": h.email,
+	} {
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		rendered[name] = strings.TrimSpace(b.String())
+	}
+
+	t := targetOf(rendered)
+	if h.typ == "link" {
+		g.push(w, pushbullet.Link{
+			Iden:  t.Device,
+			Tag:   t.Channel,
+			Email: t.Email,
+			Type:  "link",
+			Title: rendered["title"],
+			URL:   rendered["url"],
+			Body:  rendered["body"],
+		})
+		return
+	}
+	g.push(w, pushbullet.Note{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "note",
+		Title: rendered["title"],
+		Body:  rendered["body"],
+	})
+}
+
+// push sends data and responds with the iden of the created push. Invalid
+// pushes are the client's fault, other failures PushBullet's.
+func (g *Gateway) push(w http.ResponseWriter, data interface{}) {
+	p, err := g.Client.Push("/pushes", data)
+	if _, invalid := err.(*pushbullet.ValidationError); invalid {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"iden": p.Iden})
+}
+
+func targetOf(fields map[string]string) pushbullet.Target {
+	return pushbullet.Target{
+		Device:  fields["device"],
+		Channel: fields["channel"],
+		Email:   fields["email"],
+	}
+}
+
+// readData decodes a JSON or form request body into template data.
+func readData(r *http.Request) (interface{}, error) {
+	ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if ct == "application/json" {
+		var data interface{}
+		dec := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+		if err := dec.Decode(&data); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	r.Body = io.NopCloser(io.LimitReader(r.Body, maxBodySize))
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{}
+	for k := range r.Form {
+		fields[k] = r.Form.Get(k)
+	}
+	return fields, nil
+}
+
+// readFields decodes a JSON object or form request body into string fields.
+func readFields(r *http.Request) (map[string]string, error) {
+	data, err := readData(r)
+	if err != nil {
+		return nil, err
+	}
+	if fields, ok := data.(map[string]string); ok {
+		return fields, nil
+	}
+	obj, ok := data.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("body must be a JSON object")
+	}
+	fields := map[string]string{}
+	for k, v := range obj {
+		if s, ok := v.(string); ok {
+			fields[k] = s
+		}
+	}
+	return fields, nil
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,233 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xconstruct/go-pushbullet"
+)
+
+var token = "SECRET"
+
+func PushbulletStub(pushes *[]map[string]string) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/me":
+			w.Write([]byte(`{"iden": "ujpah72o0", "max_upload_size": 1024}`))
+		case "/upload-request":
+			json.NewEncoder(w).Encode(map[string]string{
+				"file_name":  "log.txt",
+				"file_type":  "text/plain",
+				"file_url":   "https://files/log.txt",
+				"upload_url": server.URL + "/upload",
+			})
+		case "/upload":
+			w.WriteHeader(http.StatusNoContent)
+		case "/pushes":
+			var push map[string]string
+			json.NewDecoder(r.Body).Decode(&push)
+			delete(push, "guid") // random
+			*pushes = append(*pushes, push)
+			w.Write([]byte(`{"iden": "push-iden"}`))
+		}
+	}))
+	return server
+}
+
+func newGateway(t *testing.T, pushes *[]map[string]string) (*Gateway, func()) {
+	server := PushbulletStub(pushes)
+	pb := pushbullet.New("API_KEY")
+	pb.Endpoint.URL = server.URL
+	g, err := New(pb, []string{token}, map[string]Hook{
+		"grafana": {
+			Type:    "link",
+			Title:   "{{.title}}",
+			Body:    "{{stripMarkdown .message}} ({{len .evalMatches}} matches)",
+			URL:     "{{.ruleUrl}}",
+			Channel: "ops",
+		},
+		"optional": {
+			Title: "{{.title}}",
+			Body:  `{{default "none" (index . "message")}}`,
+		},
+	})
+	assert.NoError(t, err)
+	return g, server.Close
+}
+
+func TestNote(t *testing.T) {
+	var pushes []map[string]string
+	g, done := newGateway(t, &pushes)
+	defer done()
+
+	req := httptest.NewRequest("POST", "/note", strings.NewReader(`{"title": "Build", "body": "passed", "device": "phone"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"iden": "push-iden"}`, rec.Body.String())
+	assert.Equal(t, []map[string]string{{"device_iden": "phone", "type": "note", "title": "Build", "body": "passed"}}, pushes)
+}
+
+func TestLinkForm(t *testing.T) {
+	var pushes []map[string]string
+	g, done := newGateway(t, &pushes)
+	defer done()
+
+	form := url.Values{"title": {"Docs"}, "url": {"https://docs.pushbullet.com"}, "email": {"a@example.com"}}
+	req := httptest.NewRequest("POST", "/link?token="+token, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, pushes, 1) {
+		assert.Equal(t, "https://docs.pushbullet.com", pushes[0]["url"])
+		assert.Equal(t, "a@example.com", pushes[0]["email"])
+	}
+}
+
+func TestFile(t *testing.T) {
+	var pushes []map[string]string
+	g, done := newGateway(t, &pushes)
+	defer done()
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	fw, _ := mw.CreateFormFile("file", "log.txt")
+	fw.Write([]byte("output"))
+	mw.WriteField("body", "nightly log")
+	mw.Close()
+	req := httptest.NewRequest("POST", "/file", &b)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.Len(t, pushes, 1) {
+		assert.Equal(t, "file", pushes[0]["type"])
+		assert.Equal(t, "https://files/log.txt", pushes[0]["file_url"])
+		assert.Equal(t, "nightly log", pushes[0]["body"])
+	}
+}
+
+func TestFileTooLarge(t *testing.T) {
+	var pushes []map[string]string
+	g, done := newGateway(t, &pushes)
+	defer done()
+
+	for _, c := range []struct {
+		size, maxFileSize int64
+	}{
+		{2048, 25 << 20}, // over the account's upload limit
+		{2048, 1500},     // over the gateway's MaxFileSize
+	} {
+		g.MaxFileSize = c.maxFileSize
+		var b bytes.Buffer
+		mw := multipart.NewWriter(&b)
+		fw, _ := mw.CreateFormFile("file", "big.bin")
+		fw.Write(make([]byte, c.size))
+		mw.Close()
+		req := httptest.NewRequest("POST", "/file", &b)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	}
+	assert.Len(t, pushes, 0)
+}
+
+func TestHook(t *testing.T) {
+	var pushes []map[string]string
+	g, done := newGateway(t, &pushes)
+	defer done()
+
+	payload := `{"title": "[Alerting] CPU", "message": "CPU **high**", "ruleUrl": "http://grafana/d/1", "evalMatches": [{}, {}]}`
+	req := httptest.NewRequest("POST", "/hooks/grafana", strings.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []map[string]string{{
+		"channel_tag": "ops",
+		"type":        "link",
+		"title":       "[Alerting] CPU",
+		"url":         "http://grafana/d/1",
+		"body":        "CPU high (2 matches)",
+	}}, pushes)
+}
+
+func TestHookOptionalKey(t *testing.T) {
+	var pushes []map[string]string
+	g, done := newGateway(t, &pushes)
+	defer done()
+
+	req := httptest.NewRequest("POST", "/hooks/optional", strings.NewReader(`{"title": "hi"}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	g.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []map[string]string{{"type": "note", "title": "hi", "body": "none"}}, pushes)
+}
+
+func TestErrors(t *testing.T) {
+	var pushes []map[string]string
+	g, done := newGateway(t, &pushes)
+	defer done()
+
+	cases := []struct {
+		method, path, auth, body string
+		code                     int
+	}{
+		{"POST", "/note", "", `{}`, http.StatusUnauthorized},
+		{"POST", "/note", "Bearer WRONG", `{}`, http.StatusUnauthorized},
+		{"GET", "/note", "Bearer " + token, `{}`, http.StatusMethodNotAllowed},
+		{"POST", "/hooks/missing", "Bearer " + token, `{}`, http.StatusNotFound},
+		{"POST", "/link", "Bearer " + token, `{}`, http.StatusBadRequest},
+		// Rejected by client-side validation.
+		{"POST", "/note", "Bearer " + token, `{}`, http.StatusBadRequest},
+		{"POST", "/link", "Bearer " + token, `{"url": "not a url"}`, http.StatusBadRequest},
+		{"POST", "/note", "Bearer " + token, `{"title": "x", "device": "a", "channel": "b"}`, http.StatusBadRequest},
+		// The hook refers to keys missing from the payload.
+		{"POST", "/hooks/grafana", "Bearer " + token, `{"title": "hi"}`, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		req.Header.Set("Content-Type", "application/json")
+		if c.auth != "" {
+			req.Header.Set("Authorization", c.auth)
+		}
+		rec := httptest.NewRecorder()
+		g.ServeHTTP(rec, req)
+		assert.Equal(t, c.code, rec.Code, c.path+" "+c.body)
+	}
+	assert.Len(t, pushes, 0)
+}
+
+func TestNewInvalid(t *testing.T) {
+	pb := pushbullet.New("API_KEY")
+	_, err := New(pb, nil, nil)
+	assert.Error(t, err)
+	_, err = New(pb, []string{token}, map[string]Hook{"bad": {Title: "{{.title"}})
+	assert.Error(t, err)
+	_, err = New(pb, []string{token}, map[string]Hook{"bad": {Type: "link"}})
+	assert.Error(t, err)
+	_, err = New(pb, []string{token}, map[string]Hook{"bad": {Type: "sms"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package pushbullet
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+)
+
+// PushBullet treats pushes with the same guid as the same push: pushing again
+// with a guid it has seen returns the existing push instead of notifying the
+// user twice. Every push payload therefore gets a guid when it is first
+// pushed, and keeps it when the request is retried by the client, a Fanout or
+// an Outbox. Callers that retry on their own must set the guid themselves.
+
+// newGUID returns a random version 4 UUID.
+func newGUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// guider is implemented by the push payloads that carry a guid.
+type guider interface {
+	// guid returns the payload's guid, if any.
+	guid() string
+	// withGUID returns a copy of the payload with a new guid if it has
+	// none yet.
+	withGUID() interface{}
+}
+
+// ensureGUID gives data a guid if it is a push payload without one.
+func ensureGUID(data interface{}) interface{} {
+	if g, ok := data.(guider); ok {
+		return g.withGUID()
+	}
+	return data
+}
+
+func (n Note) guid() string {
+	return n.GUID
+}
+
+func (n Note) withGUID() interface{} {
+	if n.GUID == "" {
+		n.GUID = newGUID()
+	}
+	return n
+}
+
+func (l Link) guid() string {
+	return l.GUID
+}
+
+func (l Link) withGUID() interface{} {
+	if l.GUID == "" {
+		l.GUID = newGUID()
+	}
+	return l
+}
+
+func (f File) guid() string {
+	return f.GUID
+}
+
+func (f File) withGUID() interface{} {
+	if f.GUID == "" {
+		f.GUID = newGUID()
+	}
+	return f
+}
+
+func (a Address) guid() string {
+	return a.GUID
+}
+
+func (a Address) withGUID() interface{} {
+	if a.GUID == "" {
+		a.GUID = newGUID()
+	}
+	return a
+}
+
+func (l List) guid() string {
+	return l.GUID
+}
+
+func (l List) withGUID() interface{} {
+	if l.GUID == "" {
+		l.GUID = newGUID()
+	}
+	return l
+}
+
+// objectWithGUID marshals data, which must marshal to a JSON object, with its
+// guid field set to guid. If guid is empty, the object's own guid is kept, or
+// a random one is set if it has none. It returns the JSON and the guid.
+func objectWithGUID(data interface{}, guid string) ([]byte, string, error) {
+	var fields map[string]interface{}
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, "", err
+	}
+	if guid == "" {
+		guid, _ = fields["guid"].(string)
+	}
+	if guid == "" {
+		guid = newGUID()
+	}
+	fields["guid"] = guid
+	if b, err = json.Marshal(fields); err != nil {
+		return nil, "", err
+	}
+	return b, guid, nil
+}
@@ -0,0 +1,120 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewGUID(t *testing.T) {
+	a, b := newGUID(), newGUID()
+	assert.Regexp(t, uuidV4, a)
+	assert.NotEqual(t, a, b)
+}
+
+func TestPushGUID(t *testing.T) {
+	server := OutboxResponseStub()
+	defer server.Close()
+	server.setOnline(true)
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := pb.PushNote(d.Iden, n.Title, n.Body)
+	assert.NoError(t, err)
+	_, err = pb.PushLink(d.Iden, l.Title, l.URL, l.Body)
+	assert.NoError(t, err)
+	note := DeviceTarget(d.Iden).note(n.Title, n.Body)
+	note.GUID = "caller-guid"
+	_, err = pb.Push("/pushes", note)
+	assert.NoError(t, err)
+	assert.NoError(t, pb.DismissPush("push-iden"))
+
+	assert.Len(t, server.guids, 4)
+	assert.Regexp(t, uuidV4, server.guids[0])
+	assert.Regexp(t, uuidV4, server.guids[1])
+	assert.NotEqual(t, server.guids[0], server.guids[1])
+	assert.Equal(t, "caller-guid", server.guids[2])
+	assert.Equal(t, "", server.guids[3])
+}
+
+func TestFanoutRetryKeepsGUID(t *testing.T) {
+	var guids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var note Note
+		json.NewDecoder(r.Body).Decode(&note)
+		guids = append(guids, note.GUID)
+		if len(guids) == 1 {
+			w.Header().Set("X-Ratelimit-Reset", strconv.FormatInt(time.Now().Unix(), 10))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"iden": "push-iden"}`))
+	}))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	results, err := pb.PushNoteMulti([]Target{ChannelTarget("news")}, n.Title, n.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "push-iden", results[0].Iden)
+	assert.Len(t, guids, 2)
+	assert.Regexp(t, uuidV4, guids[0])
+	assert.Equal(t, guids[0], guids[1])
+}
+
+func TestOutboxUsesPushGUID(t *testing.T) {
+	pb := New(k)
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+	o, err := OpenOutbox(pb, path, nil)
+	assert.NoError(t, err)
+	defer o.Close()
+
+	note := DeviceTarget(d.Iden).note(n.Title, n.Body)
+	note.GUID = "caller-guid"
+	guid, err := o.Push("", "/pushes", note)
+	assert.NoError(t, err)
+	assert.Equal(t, "caller-guid", guid)
+
+	guid, err = o.Push("explicit-guid", "/pushes", note)
+	assert.NoError(t, err)
+	assert.Equal(t, "explicit-guid", guid)
+
+	guid, err = o.PushNote(d.Iden, n.Title, n.Body)
+	assert.NoError(t, err)
+	assert.Regexp(t, uuidV4, guid)
+}
+
+func TestScheduledPushGUID(t *testing.T) {
+	guidOf := func(p ScheduledPush) string {
+		var push struct {
+			GUID string `json:"guid"`
+		}
+		json.Unmarshal(p.payload().(json.RawMessage), &push)
+		return push.GUID
+	}
+
+	once := ScheduledPush{EndPoint: "/pushes", Data: json.RawMessage(`{"type": "note", "guid": "caller-guid"}`)}
+	assert.Equal(t, "caller-guid", guidOf(once))
+	once.Data = json.RawMessage(`{"type": "note"}`)
+	assert.Regexp(t, uuidV4, guidOf(once))
+
+	// Every occurrence of a recurring push is a new push.
+	daily := ScheduledPush{EndPoint: "/pushes", Spec: "@daily", Data: json.RawMessage(`{"type": "note", "guid": "caller-guid"}`)}
+	a, b := guidOf(daily), guidOf(daily)
+	assert.Regexp(t, uuidV4, a)
+	assert.NotEqual(t, a, b)
+
+	eph := ScheduledPush{EndPoint: "/ephemerals", Data: json.RawMessage(`{"type": "push"}`)}
+	assert.Equal(t, eph.Data, eph.payload())
+}
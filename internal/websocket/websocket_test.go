@@ -0,0 +1,105 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func frame(fin bool, op byte, payload []byte) []byte {
+	b0 := op
+	if fin {
+		b0 |= 0x80
+	}
+	f := []byte{b0}
+	switch n := len(payload); {
+	case n < 126:
+		f = append(f, byte(n))
+	case n <= 0xffff:
+		f = append(f, 126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		f = append(append(f, 127), ext[:]...)
+	}
+	return append(f, payload...)
+}
+
+func Server(t *testing.T, frames [][]byte, got chan<- []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "websocket", r.Header.Get("Upgrade"))
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + AcceptKey(r.Header.Get("Sec-Websocket-Key")) + "\r\n\r\n")
+		for _, f := range frames {
+			rw.Write(f)
+		}
+		rw.Flush()
+
+		c := &Conn{conn: conn, br: bufio.NewReader(rw)}
+		for {
+			_, op, payload, err := c.readFrame()
+			if err != nil {
+				close(got)
+				return
+			}
+			got <- append([]byte{op}, payload...)
+		}
+	}))
+}
+
+func TestReadMessage(t *testing.T) {
+	big := bytes.Repeat([]byte("x"), 70000)
+	got := make(chan []byte, 10)
+	server := Server(t, [][]byte{
+		frame(true, opText, []byte(`{"type":"nop"}`)),
+		frame(true, opPing, []byte("hi")),
+		frame(false, opText, []byte("frag")),
+		frame(true, opContinuation, []byte("mented")),
+		frame(true, opBinary, big),
+		frame(true, opClose, nil),
+	}, got)
+	defer server.Close()
+
+	c, err := Dial(strings.Replace(server.URL, "http", "ws", 1)+"/websocket/KEY", 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer c.Close()
+
+	msg, err := c.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"nop"}`, string(msg))
+	msg, err = c.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "fragmented", string(msg))
+	msg, err = c.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, big, msg)
+	_, err = c.ReadMessage()
+	assert.Equal(t, ErrClosed, err)
+
+	assert.Equal(t, append([]byte{opPong}, "hi"...), <-got)
+	assert.Equal(t, []byte{opClose}, <-got)
+}
+
+func TestDialErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := Dial(strings.Replace(server.URL, "http", "ws", 1), 0)
+	assert.EqualError(t, err, "websocket: handshake failed: 404 Not Found")
+	_, err = Dial("http://example.com", 0)
+	assert.Error(t, err)
+}
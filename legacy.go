@@ -0,0 +1,144 @@
+package pushbullet
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Address exposes the fields of the legacy Pushbullet push type=address
+type Address struct {
+	Iden    string `json:"device_iden,omitempty"`
+	Tag     string `json:"channel_tag,omitempty"`
+	Email   string `json:"email,omitempty"`
+	Type    string `json:"type"`
+	GUID    string `json:"guid,omitempty"`
+	Name    string `json:"name"`
+	Address string `json:"address"`
+}
+
+// A ListItem is an entry of a checklist push.
+type ListItem struct {
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+}
+
+// List exposes the fields of the legacy Pushbullet push type=list
+type List struct {
+	Iden  string     `json:"device_iden,omitempty"`
+	Tag   string     `json:"channel_tag,omitempty"`
+	Email string     `json:"email,omitempty"`
+	Type  string     `json:"type"`
+	GUID  string     `json:"guid,omitempty"`
+	Title string     `json:"title"`
+	Items []ListItem `json:"items"`
+}
+
+// MapsURL returns a link that shows address on a map.
+func MapsURL(address string) string {
+	return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(address)
+}
+
+// FormatList renders checklist items as text, one "[x] item" or "[ ] item"
+// per line.
+func FormatList(items []ListItem) string {
+	lines := make([]string, len(items))
+	for i, it := range items {
+		mark := " "
+		if it.Checked {
+			mark = "x"
+		}
+		lines[i] = fmt.Sprintf("[%s] %s", mark, it.Text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (t Target) address(name, address string) Address {
+	return Address{
+		Iden:    t.Device,
+		Tag:     t.Channel,
+		Email:   t.Email,
+		Type:    "address",
+		Name:    name,
+		Address: address,
+	}
+}
+
+func (t Target) list(title string, items []ListItem) List {
+	return List{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "list",
+		Title: title,
+		Items: items,
+	}
+}
+
+// pushLegacy pushes data of a legacy type. The API no longer accepts these
+// everywhere, so if it rejects the request as invalid, fallback is pushed
+// instead.
+func (c *Client) pushLegacy(data, fallback interface{}) (*Push, error) {
+	push, err := c.Push("/pushes", data)
+	if e, ok := err.(*ErrResponse); ok && e.Type == "invalid_request" {
+		return c.Push("/pushes", fallback)
+	}
+	return push, err
+}
+
+func (c *Client) pushAddress(t Target, name, address string) (*Push, error) {
+	title := name
+	if title == "" {
+		title = address
+	}
+	return c.pushLegacy(t.address(name, address), t.link(title, MapsURL(address), address))
+}
+
+func (c *Client) pushList(t Target, title string, items []ListItem) (*Push, error) {
+	return c.pushLegacy(t.list(title, items), t.note(title, FormatList(items)))
+}
+
+// PushAddress pushes an address to a specific PushBullet device. Where the
+// address type is no longer accepted, a link to a map is pushed instead.
+func (c *Client) PushAddress(iden, name, address string) (*Push, error) {
+	return c.pushAddress(DeviceTarget(iden), name, address)
+}
+
+// PushAddressToChannel pushes an address to a specific PushBullet channel.
+// Where the address type is no longer accepted, a link to a map is pushed
+// instead.
+func (c *Client) PushAddressToChannel(tag, name, address string) (*Push, error) {
+	return c.pushAddress(ChannelTarget(tag), name, address)
+}
+
+// PushList pushes a checklist to a specific PushBullet device. Where the
+// list type is no longer accepted, the items are pushed as a note.
+func (c *Client) PushList(iden, title string, items []ListItem) (*Push, error) {
+	return c.pushList(DeviceTarget(iden), title, items)
+}
+
+// PushListToChannel pushes a checklist to a specific PushBullet channel.
+// Where the list type is no longer accepted, the items are pushed as a note.
+func (c *Client) PushListToChannel(tag, title string, items []ListItem) (*Push, error) {
+	return c.pushList(ChannelTarget(tag), title, items)
+}
+
+// PushAddress sends an address to the specific device
+func (d *Device) PushAddress(name, address string) (*Push, error) {
+	return d.Client.PushAddress(d.Iden, name, address)
+}
+
+// PushList sends a checklist to the specific device
+func (d *Device) PushList(title string, items []ListItem) (*Push, error) {
+	return d.Client.PushList(d.Iden, title, items)
+}
+
+// PushAddress sends an address to the specific Channel
+func (s *Subscription) PushAddress(name, address string) (*Push, error) {
+	return s.Client.PushAddressToChannel(s.Channel.Tag, name, address)
+}
+
+// PushList sends a checklist to the specific Channel
+func (s *Subscription) PushList(title string, items []ListItem) (*Push, error) {
+	return s.Client.PushListToChannel(s.Channel.Tag, title, items)
+}
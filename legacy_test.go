@@ -0,0 +1,113 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func LegacyResponseStub(rejectLegacy bool, pushed *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+		delete(data, "guid") // random, see guid_test.go
+		*pushed = append(*pushed, data)
+		w.Header().Set("Content-Type", "application/json")
+		if rejectLegacy && (data["type"] == "address" || data["type"] == "list") {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": {"type": "invalid_request", "message": "Invalid push type.", "cat": "~(=^‥^)ノ"}}`))
+			return
+		}
+		w.Write([]byte(`{"iden": "legacy-push"}`))
+	}))
+}
+
+func TestPushAddress(t *testing.T) {
+	var pushed []map[string]interface{}
+	server := LegacyResponseStub(false, &pushed)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	push, err := pb.PushAddress(d.Iden, "Office", "1 Main St, Springfield")
+	assert.NoError(t, err)
+	assert.Equal(t, "legacy-push", push.Iden)
+	assert.Equal(t, []map[string]interface{}{{
+		"device_iden": d.Iden,
+		"type":        "address",
+		"name":        "Office",
+		"address":     "1 Main St, Springfield",
+	}}, pushed)
+}
+
+func TestPushAddressFallback(t *testing.T) {
+	var pushed []map[string]interface{}
+	server := LegacyResponseStub(true, &pushed)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := pb.PushAddressToChannel("ops", "Office", "1 Main St, Springfield")
+	assert.NoError(t, err)
+	assert.Len(t, pushed, 2)
+	assert.Equal(t, map[string]interface{}{
+		"channel_tag": "ops",
+		"type":        "link",
+		"title":       "Office",
+		"url":         "https://www.google.com/maps/search/?api=1&query=1+Main+St%2C+Springfield",
+		"body":        "1 Main St, Springfield",
+	}, pushed[1])
+}
+
+func TestPushList(t *testing.T) {
+	var pushed []map[string]interface{}
+	server := LegacyResponseStub(false, &pushed)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	items := []ListItem{{Text: "milk", Checked: true}, {Text: "eggs"}}
+	_, err := pb.PushList(d.Iden, "Groceries", items)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{
+		"device_iden": d.Iden,
+		"type":        "list",
+		"title":       "Groceries",
+		"items": []interface{}{
+			map[string]interface{}{"text": "milk", "checked": true},
+			map[string]interface{}{"text": "eggs", "checked": false},
+		},
+	}}, pushed)
+}
+
+func TestPushListFallback(t *testing.T) {
+	var pushed []map[string]interface{}
+	server := LegacyResponseStub(true, &pushed)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	dev := &Device{Iden: d.Iden, Client: pb}
+	_, err := dev.PushList("Groceries", []ListItem{{Text: "milk", Checked: true}, {Text: "eggs"}})
+	assert.NoError(t, err)
+	assert.Len(t, pushed, 2)
+	assert.Equal(t, map[string]interface{}{
+		"device_iden": d.Iden,
+		"type":        "note",
+		"title":       "Groceries",
+		"body":        "[x] milk\n[ ] eggs",
+	}, pushed[1])
+}
+
+func TestPushLegacyOtherError(t *testing.T) {
+	server := PushbulletErrResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := pb.PushAddress(d.Iden, "Office", "1 Main St")
+	assert.EqualError(t, err, "500 Internal Server Error")
+}
@@ -0,0 +1,276 @@
+package pushbullet
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LogHandlerOptions configures a LogHandler.
+type LogHandlerOptions struct {
+	// Level is the minimum level of records that are pushed. Defaults to
+	// slog.LevelError.
+	Level slog.Leveler
+
+	// Device is the iden of the device to push to. If Channel is set the
+	// records are pushed to that channel instead. If both are empty they go
+	// to all of the user's devices.
+	Device  string
+	Channel string
+
+	// BufferSize is the number of records that may wait for delivery before
+	// new records are dropped. Defaults to 64.
+	BufferSize int
+
+	// GroupWindow is how long to wait for further records after one arrives;
+	// all records of a burst are sent as a single push. Defaults to 5
+	// seconds.
+	GroupWindow time.Duration
+
+	// OnError, if set, is called when a push fails.
+	OnError func(err error)
+}
+
+// A LogHandler is a slog.Handler that pushes log records at or above a level
+// as notes. Records are delivered asynchronously, so logging never blocks on
+// PushBullet; records that do not fit into the buffer are dropped and counted.
+type LogHandler struct {
+	opts   LogHandlerOptions
+	attrs  []slog.Attr
+	groups []string
+	sink   *logSink
+}
+
+type logSink struct {
+	client  *Client
+	opts    LogHandlerOptions
+	records chan logEntry
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+	dropped uint64
+}
+
+type logEntry struct {
+	level slog.Level
+	title string
+	body  string
+}
+
+// NewLogHandler creates a LogHandler pushing with the client. opts may be nil.
+func NewLogHandler(c *Client, opts *LogHandlerOptions) *LogHandler {
+	var o LogHandlerOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelError
+	}
+	if o.BufferSize <= 0 {
+		o.BufferSize = 64
+	}
+	if o.GroupWindow == 0 {
+		o.GroupWindow = 5 * time.Second
+	}
+
+	s := &logSink{
+		client:  c,
+		opts:    o,
+		records: make(chan logEntry, o.BufferSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return &LogHandler{opts: o, sink: s}
+}
+
+// Enabled reports whether records of the given level are pushed.
+func (h *LogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+// Handle queues the record for delivery. It never blocks; if the buffer is
+// full or the handler is closed the record is dropped.
+func (h *LogHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format(time.RFC3339))
+		b.WriteString("\n")
+	}
+	prefix := strings.Join(h.groups, ".")
+	for _, a := range h.attrs {
+		writeLogAttr(&b, "", a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		writeLogAttr(&b, prefix, a)
+		return true
+	})
+
+	e := logEntry{
+		level: r.Level,
+		title: r.Level.String() + ": " + r.Message,
+		body:  strings.TrimSuffix(b.String(), "\n"),
+	}
+	select {
+	case <-h.sink.done:
+		atomic.AddUint64(&h.sink.dropped, 1)
+		return nil
+	default:
+	}
+	select {
+	case h.sink.records <- e:
+	default:
+		atomic.AddUint64(&h.sink.dropped, 1)
+	}
+	return nil
+}
+
+// WithAttrs returns a handler that adds attrs to every record.
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	prefix := strings.Join(h.groups, ".")
+	h2.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		if prefix != "" {
+			a.Key = prefix + "." + a.Key
+		}
+		h2.attrs = append(h2.attrs, a)
+	}
+	return &h2
+}
+
+// WithGroup returns a handler that qualifies the keys of all following
+// attributes with name.
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = append(append([]string(nil), h.groups...), name)
+	return &h2
+}
+
+// Dropped returns the number of records that were dropped because the buffer
+// was full or the handler was closed.
+func (h *LogHandler) Dropped() uint64 {
+	return atomic.LoadUint64(&h.sink.dropped)
+}
+
+// Close stops accepting records and waits until the buffered ones have been
+// pushed. It affects all handlers derived from h.
+func (h *LogHandler) Close() error {
+	h.sink.once.Do(func() { close(h.sink.done) })
+	<-h.sink.stopped
+	return nil
+}
+
+func writeLogAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	key := a.Key
+	if prefix != "" && key != "" {
+		key = prefix + "." + key
+	} else if key == "" {
+		key = prefix
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			writeLogAttr(b, key, ga)
+		}
+		return
+	}
+	fmt.Fprintf(b, "%s=%s\n", key, a.Value)
+}
+
+func (s *logSink) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case e := <-s.records:
+			burst := []logEntry{e}
+			timer := time.NewTimer(s.opts.GroupWindow)
+		collect:
+			for {
+				select {
+				case e := <-s.records:
+					burst = append(burst, e)
+				case <-timer.C:
+					break collect
+				case <-s.done:
+					timer.Stop()
+					break collect
+				}
+			}
+			s.push(burst)
+		case <-s.done:
+			var rest []logEntry
+			for {
+				select {
+				case e := <-s.records:
+					rest = append(rest, e)
+				default:
+					if len(rest) > 0 {
+						s.push(rest)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// push sends a burst of records as one note, titled after the most severe
+// record. Title and body are cut to MaxTitleLength and MaxBodyLength, so that
+// long messages still pass validation.
+func (s *logSink) push(burst []logEntry) {
+	top := burst[0]
+	for _, e := range burst[1:] {
+		if e.level > top.level {
+			top = e
+		}
+	}
+
+	title, body := truncate(MaxTitleLength, top.title), top.body
+	if len(burst) > 1 {
+		more := fmt.Sprintf(" (+%d more)", len(burst)-1)
+		title = truncate(MaxTitleLength-len(more), top.title) + more
+		parts := make([]string, len(burst))
+		for i, e := range burst {
+			parts[i] = e.title
+			if e.body != "" {
+				parts[i] += "\n" + e.body
+			}
+		}
+		body = strings.Join(parts, "\n\n")
+	}
+	body = truncate(MaxBodyLength, body)
+
+	var err error
+	if s.opts.Channel != "" {
+		_, err = s.client.PushNoteToChannel(s.opts.Channel, title, body)
+	} else {
+		_, err = s.client.PushNote(s.opts.Device, title, body)
+	}
+	if err != nil && s.opts.OnError != nil {
+		s.opts.OnError(err)
+	}
+}
+
+// truncate shortens s to at most n characters, marking the cut with an
+// ellipsis.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
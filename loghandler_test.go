@@ -0,0 +1,171 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func LogResponseStub(notes *[]Note, mu *sync.Mutex, block chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if block != nil {
+			<-block
+		}
+		var note Note
+		json.NewDecoder(r.Body).Decode(&note)
+		mu.Lock()
+		*notes = append(*notes, note)
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+}
+
+func TestLogHandler(t *testing.T) {
+	var mu sync.Mutex
+	var notes []Note
+	server := LogResponseStub(&notes, &mu, nil)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	h := NewLogHandler(pb, &LogHandlerOptions{Channel: "ops", GroupWindow: time.Hour})
+	logger := slog.New(h).With("service", "api").WithGroup("req")
+	logger.Info("ignored")
+	logger.Error("database down", "host", "db1", slog.Group("retry", "n", 3))
+	logger.Warn("also ignored")
+	assert.NoError(t, h.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, notes, 1) {
+		assert.Equal(t, "ops", notes[0].Tag)
+		assert.Equal(t, "ERROR: database down", notes[0].Title)
+		assert.Contains(t, notes[0].Body, "service=api\nreq.host=db1\nreq.retry.n=3")
+	}
+	assert.Equal(t, uint64(0), h.Dropped())
+}
+
+func TestLogHandlerBurst(t *testing.T) {
+	var mu sync.Mutex
+	var notes []Note
+	server := LogResponseStub(&notes, &mu, nil)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	h := NewLogHandler(pb, &LogHandlerOptions{Device: d.Iden, Level: slog.LevelWarn, GroupWindow: 50 * time.Millisecond})
+	defer h.Close()
+	logger := slog.New(h)
+	logger.Warn("slow query")
+	logger.Error("timeout")
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		got := len(notes)
+		mu.Unlock()
+		if got > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, notes, 1) {
+		assert.Equal(t, d.Iden, notes[0].Iden)
+		assert.Equal(t, "ERROR: timeout (+1 more)", notes[0].Title)
+		assert.Contains(t, notes[0].Body, "WARN: slow query")
+	}
+}
+
+func TestLogHandlerDrops(t *testing.T) {
+	var mu sync.Mutex
+	var notes []Note
+	block := make(chan struct{})
+	server := LogResponseStub(&notes, &mu, block)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	h := NewLogHandler(pb, &LogHandlerOptions{BufferSize: 1, GroupWindow: time.Millisecond})
+	logger := slog.New(h)
+	logger.Error("first")
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		logger.Error("flood")
+	}
+	close(block)
+	assert.NoError(t, h.Close())
+	assert.Equal(t, uint64(4), h.Dropped())
+	logger.Error("after close")
+	assert.Equal(t, uint64(5), h.Dropped())
+}
+
+func TestLogHandlerLongMessage(t *testing.T) {
+	var mu sync.Mutex
+	var notes []Note
+	server := LogResponseStub(&notes, &mu, nil)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	var errs []error
+	h := NewLogHandler(pb, &LogHandlerOptions{
+		GroupWindow: time.Hour,
+		OnError:     func(err error) { errs = append(errs, err) },
+	})
+	logger := slog.New(h)
+	long := strings.Repeat("ü", 300)
+	logger.Error(long, "detail", strings.Repeat("x", 5000))
+	assert.NoError(t, h.Close())
+
+	assert.Empty(t, errs)
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, notes, 1) {
+		assert.Equal(t, MaxTitleLength, utf8.RuneCountInString(notes[0].Title))
+		assert.True(t, strings.HasSuffix(notes[0].Title, "üü…"))
+		assert.Equal(t, MaxBodyLength, utf8.RuneCountInString(notes[0].Body))
+	}
+}
+
+func TestLogHandlerLongBurst(t *testing.T) {
+	var mu sync.Mutex
+	var notes []Note
+	server := LogResponseStub(&notes, &mu, nil)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	h := NewLogHandler(pb, &LogHandlerOptions{GroupWindow: 50 * time.Millisecond})
+	defer h.Close()
+	logger := slog.New(h)
+	long := strings.Repeat("ü", 300)
+	logger.Error(long)
+	logger.Error(long)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		got := len(notes)
+		mu.Unlock()
+		if got > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(t, notes, 1) {
+		assert.Equal(t, MaxTitleLength, utf8.RuneCountInString(notes[0].Title))
+		assert.True(t, strings.HasSuffix(notes[0].Title, "… (+1 more)"))
+	}
+}
@@ -0,0 +1,101 @@
+package pushbullet
+
+import (
+	"net/url"
+)
+
+// A Grant is the access a user has given an OAuth app to their account.
+type Grant struct {
+	Iden     string       `json:"iden"`
+	Active   bool         `json:"active"`
+	Created  Timestamp    `json:"created"`
+	Modified Timestamp    `json:"modified"`
+	Client   *OAuthClient `json:"client"`
+}
+
+// An OAuthClient is an OAuth app registered with PushBullet. Apps listed in
+// a Grant only carry their public fields.
+type OAuthClient struct {
+	Iden          string    `json:"iden"`
+	Active        bool      `json:"active"`
+	Created       Timestamp `json:"created"`
+	Modified      Timestamp `json:"modified"`
+	Name          string    `json:"name"`
+	ImageUrl      string    `json:"image_url"`
+	WebsiteUrl    string    `json:"website_url"`
+	RedirectUri   string    `json:"redirect_uri,omitempty"`
+	AllowedOrigin string    `json:"allowed_origin,omitempty"`
+	ClientId      string    `json:"client_id,omitempty"`
+	ClientSecret  string    `json:"client_secret,omitempty"`
+}
+
+// oauthClientSettings are the fields of an OAuthClient that can be set.
+type oauthClientSettings struct {
+	Name          string `json:"name,omitempty"`
+	ImageUrl      string `json:"image_url,omitempty"`
+	WebsiteUrl    string `json:"website_url,omitempty"`
+	RedirectUri   string `json:"redirect_uri,omitempty"`
+	AllowedOrigin string `json:"allowed_origin,omitempty"`
+}
+
+func (oc *OAuthClient) settings() oauthClientSettings {
+	return oauthClientSettings{
+		Name:          oc.Name,
+		ImageUrl:      oc.ImageUrl,
+		WebsiteUrl:    oc.WebsiteUrl,
+		RedirectUri:   oc.RedirectUri,
+		AllowedOrigin: oc.AllowedOrigin,
+	}
+}
+
+type grantResponse struct {
+	Grants []*Grant
+}
+
+type oauthClientResponse struct {
+	Clients []*OAuthClient
+}
+
+// Grants fetches the OAuth apps the user has given access to their account.
+func (c *Client) Grants() ([]*Grant, error) {
+	grantResp, err := get[grantResponse](c, "/grants", nil)
+	if err != nil {
+		return nil, err
+	}
+	return grantResp.Grants, nil
+}
+
+// RevokeGrant revokes the grant with the given iden. The app's access tokens
+// stop working.
+func (c *Client) RevokeGrant(iden string) error {
+	return c.exec("DELETE", "/grants/"+url.PathEscape(iden), nil, nil, nil)
+}
+
+// OAuthClients fetches the OAuth apps the user has registered.
+func (c *Client) OAuthClients() ([]*OAuthClient, error) {
+	clientResp, err := get[oauthClientResponse](c, "/clients", nil)
+	if err != nil {
+		return nil, err
+	}
+	return clientResp.Clients, nil
+}
+
+// CreateOAuthClient registers an OAuth app with the name, image, website,
+// redirect URI and allowed origin of oc. The returned app includes its
+// client_id and client_secret.
+func (c *Client) CreateOAuthClient(oc *OAuthClient) (*OAuthClient, error) {
+	return post[OAuthClient](c, "/clients", oc.settings())
+}
+
+// UpdateOAuthClient changes the OAuth app with the given iden to the name,
+// image, website, redirect URI and allowed origin of oc. Empty fields are
+// left unchanged.
+func (c *Client) UpdateOAuthClient(iden string, oc *OAuthClient) (*OAuthClient, error) {
+	return post[OAuthClient](c, "/clients/"+url.PathEscape(iden), oc.settings())
+}
+
+// DeleteOAuthClient deletes the OAuth app with the given iden, revoking all
+// access given to it.
+func (c *Client) DeleteOAuthClient(iden string) error {
+	return c.exec("DELETE", "/clients/"+url.PathEscape(iden), nil, nil, nil)
+}
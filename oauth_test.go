@@ -0,0 +1,118 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var oc = &OAuthClient{
+	Iden:          "ujpah72o0sjAoRtnM0jc",
+	Active:        true,
+	Created:       ts("1412047948.579029"),
+	Modified:      ts("1412047948.579031"),
+	Name:          "Hello World",
+	ImageUrl:      "https://static.pushbullet.com/missing-image/55a7dc-45",
+	WebsiteUrl:    "https://www.example.com",
+	RedirectUri:   "https://www.example.com/callback",
+	AllowedOrigin: "https://www.example.com",
+	ClientId:      "RTnmDbuSUVKYvcsXIdpxHnCOfhNWgHyl",
+	ClientSecret:  "hGKkmxGSOUWgxlOLCyHWYqQBmjhDWDvd",
+}
+
+var gr = &Grant{
+	Iden:     "ujxPklLhvyKsjAvkMyTVh6",
+	Active:   true,
+	Created:  ts("1412047948.579029"),
+	Modified: ts("1412047948.579031"),
+	Client: &OAuthClient{
+		Iden:       oc.Iden,
+		Name:       oc.Name,
+		ImageUrl:   oc.ImageUrl,
+		WebsiteUrl: oc.WebsiteUrl,
+	},
+}
+
+func TestGrants(t *testing.T) {
+	b, _ := json.Marshal(gr)
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, `{"grants": [`+string(b)+`]}`)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	grants, err := pb.Grants()
+	assert.NoError(t, err)
+	assert.Equal(t, []*Grant{gr}, grants)
+
+	assert.NoError(t, pb.RevokeGrant(gr.Iden))
+	assert.Equal(t, []recordedRequest{
+		{"GET", "/grants", ""},
+		{"DELETE", "/grants/" + gr.Iden, ""},
+	}, reqs)
+}
+
+func TestOAuthClients(t *testing.T) {
+	b, _ := json.Marshal(oc)
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, `{"clients": [`+string(b)+`]}`)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	clients, err := pb.OAuthClients()
+	assert.NoError(t, err)
+	assert.Equal(t, []*OAuthClient{oc}, clients)
+	assert.NoError(t, pb.DeleteOAuthClient(oc.Iden))
+	assert.Equal(t, []recordedRequest{
+		{"GET", "/clients", ""},
+		{"DELETE", "/clients/" + oc.Iden, ""},
+	}, reqs)
+}
+
+func TestCreateAndUpdateOAuthClient(t *testing.T) {
+	b, _ := json.Marshal(oc)
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, string(b))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	created, err := pb.CreateOAuthClient(&OAuthClient{
+		Name:        oc.Name,
+		WebsiteUrl:  oc.WebsiteUrl,
+		RedirectUri: oc.RedirectUri,
+		ClientId:    "ignored",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, oc, created)
+
+	_, err = pb.UpdateOAuthClient(oc.Iden, &OAuthClient{Name: "Renamed"})
+	assert.NoError(t, err)
+
+	assert.Len(t, reqs, 2)
+	assert.Equal(t, "POST", reqs[0].Method)
+	assert.Equal(t, "/clients", reqs[0].URI)
+	assert.JSONEq(t, `{
+		"name": "Hello World",
+		"website_url": "https://www.example.com",
+		"redirect_uri": "https://www.example.com/callback"
+	}`, reqs[0].Body)
+	assert.Equal(t, "/clients/"+oc.Iden, reqs[1].URI)
+	assert.JSONEq(t, `{"name": "Renamed"}`, reqs[1].Body)
+}
+
+func TestGrantsError(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := pb.Grants()
+	assert.Equal(t, e, err)
+	assert.Equal(t, e, pb.RevokeGrant(gr.Iden))
+	_, err = pb.OAuthClients()
+	assert.Equal(t, e, err)
+}
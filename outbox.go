@@ -0,0 +1,309 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrOutboxClosed is returned when pushing to an outbox that has been closed.
+var ErrOutboxClosed = errors.New("Outbox closed")
+
+// maxDelivered is the number of delivered guids an Outbox remembers for
+// deduplication.
+const maxDelivered = 256
+
+type outboxEntry struct {
+	GUID     string          `json:"guid"`
+	EndPoint string          `json:"endpoint"`
+	Data     json.RawMessage `json:"data"`
+}
+
+type outboxState struct {
+	Pending   []outboxEntry `json:"pending"`
+	Delivered []string      `json:"delivered"`
+}
+
+// OutboxOptions configures an Outbox.
+type OutboxOptions struct {
+	// RetryInterval is how long to wait before retrying after a failed
+	// delivery. Defaults to 30 seconds.
+	RetryInterval time.Duration
+
+	// OnDrop, if set, is called for pushes that PushBullet rejected and
+	// which are therefore removed from the queue without being delivered.
+	OnDrop func(guid string, err error)
+}
+
+// An Outbox queues pushes on disk and delivers them in order in the
+// background, so pushes made while PushBullet is unreachable are sent once
+// connectivity returns. Each push carries a guid; pushes with a guid that is
+// already queued or was recently delivered are ignored.
+type Outbox struct {
+	Client *Client
+
+	opts OutboxOptions
+	path string
+
+	mu     sync.Mutex
+	state  outboxState
+	closed bool
+
+	deliverMu sync.Mutex
+	notify    chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+}
+
+// OpenOutbox opens the outbox persisted at path, creating it if it does not
+// exist, and starts delivering its pending pushes with the client. opts may be
+// nil.
+func OpenOutbox(c *Client, path string, opts *OutboxOptions) (*Outbox, error) {
+	var oo OutboxOptions
+	if opts != nil {
+		oo = *opts
+	}
+	if oo.RetryInterval == 0 {
+		oo.RetryInterval = 30 * time.Second
+	}
+
+	o := &Outbox{
+		Client:  c,
+		opts:    oo,
+		path:    path,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &o.state); err != nil {
+			return nil, err
+		}
+	}
+
+	go o.run()
+	o.wake()
+	return o, nil
+}
+
+// Push queues data for delivery to endPoint, like Client.Push. If guid is
+// empty, the guid of data is used, or a random one is generated if it has
+// none. The data must marshal to a JSON object; its guid field is set to the
+// push's guid. Push returns the guid once the push has been persisted.
+// Invalid payloads are rejected here rather than on delivery.
+func (o *Outbox) Push(guid, endPoint string, data interface{}) (string, error) {
+	if err := o.Client.validate(data); err != nil {
+		return "", err
+	}
+	b, guid, err := objectWithGUID(data, guid)
+	if err != nil {
+		return "", err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return "", ErrOutboxClosed
+	}
+	if o.known(guid) {
+		return guid, nil
+	}
+	o.state.Pending = append(o.state.Pending, outboxEntry{guid, endPoint, b})
+	if err := o.save(); err != nil {
+		o.state.Pending = o.state.Pending[:len(o.state.Pending)-1]
+		return "", err
+	}
+	o.wake()
+	return guid, nil
+}
+
+// PushNote queues a note with title and body for the device with the given
+// iden.
+func (o *Outbox) PushNote(iden, title, body string) (string, error) {
+	return o.Push("", "/pushes", Note{
+		Iden:  iden,
+		Type:  "note",
+		Title: title,
+		Body:  body,
+	})
+}
+
+// PushLink queues a link with title, url and body for the device with the
+// given iden.
+func (o *Outbox) PushLink(iden, title, u, body string) (string, error) {
+	return o.Push("", "/pushes", Link{
+		Iden:  iden,
+		Type:  "link",
+		Title: title,
+		URL:   u,
+		Body:  body,
+	})
+}
+
+// Len returns the number of pushes waiting to be delivered.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.state.Pending)
+}
+
+// Flush delivers all pending pushes in order. It stops at the first push
+// that cannot be delivered and returns its error; the push stays queued.
+func (o *Outbox) Flush() error {
+	return o.deliver()
+}
+
+// Close stops background delivery. Pending pushes remain on disk and are
+// delivered the next time the outbox is opened. Call Flush before Close to
+// try to deliver them first.
+func (o *Outbox) Close() error {
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+
+	close(o.done)
+	<-o.stopped
+	return nil
+}
+
+func (o *Outbox) run() {
+	defer close(o.stopped)
+	var retry <-chan time.Time
+	for {
+		select {
+		case <-o.done:
+			return
+		case <-o.notify:
+		case <-retry:
+		}
+		retry = nil
+		if err := o.deliver(); err != nil {
+			retry = time.After(o.retryDelay(err))
+		}
+	}
+}
+
+func (o *Outbox) retryDelay(err error) time.Duration {
+	if rl, ok := err.(*RateLimitError); ok {
+		if d := time.Until(rl.Reset); d > 0 {
+			return d
+		}
+	}
+	return o.opts.RetryInterval
+}
+
+func (o *Outbox) wake() {
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends pending pushes until the queue is empty or a push fails
+// with an error that is worth retrying.
+func (o *Outbox) deliver() error {
+	o.deliverMu.Lock()
+	defer o.deliverMu.Unlock()
+
+	for {
+		o.mu.Lock()
+		if len(o.state.Pending) == 0 {
+			o.mu.Unlock()
+			return nil
+		}
+		entry := o.state.Pending[0]
+		o.mu.Unlock()
+
+		_, err := o.Client.Push(entry.EndPoint, entry.Data)
+		if err != nil && !rejected(err) {
+			return err
+		}
+
+		o.mu.Lock()
+		o.state.Pending = o.state.Pending[1:]
+		if err == nil {
+			o.state.Delivered = append(o.state.Delivered, entry.GUID)
+			if len(o.state.Delivered) > maxDelivered {
+				o.state.Delivered = o.state.Delivered[len(o.state.Delivered)-maxDelivered:]
+			}
+		}
+		saveErr := o.save()
+		o.mu.Unlock()
+
+		if err != nil && o.opts.OnDrop != nil {
+			o.opts.OnDrop(entry.GUID, err)
+		}
+		if saveErr != nil {
+			return saveErr
+		}
+	}
+}
+
+// rejected reports whether err means that PushBullet refused the push, or
+// that it is invalid, so that sending it again is pointless. Network and
+// server errors are worth a retry.
+func rejected(err error) bool {
+	switch e := err.(type) {
+	case *ErrResponse:
+		return !e.Temporary()
+	case *ValidationError:
+		return true
+	}
+	return false
+}
+
+// known reports whether guid is pending or was delivered recently. o.mu must
+// be held.
+func (o *Outbox) known(guid string) bool {
+	for _, e := range o.state.Pending {
+		if e.GUID == guid {
+			return true
+		}
+	}
+	for _, g := range o.state.Delivered {
+		if g == guid {
+			return true
+		}
+	}
+	return false
+}
+
+// save writes the outbox state to disk. o.mu must be held.
+func (o *Outbox) save() error {
+	return writeJSONFile(o.path, o.state)
+}
+
+// writeJSONFile atomically replaces the file at path with v encoded as JSON.
+func writeJSONFile(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
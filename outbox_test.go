@@ -0,0 +1,182 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type outboxServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	online bool
+	guids  []string
+}
+
+func OutboxResponseStub() *outboxServer {
+	s := &outboxServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if !s.online {
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		var push struct {
+			GUID string `json:"guid"`
+		}
+		json.NewDecoder(r.Body).Decode(&push)
+		s.guids = append(s.guids, push.GUID)
+		w.Write([]byte(`{}`))
+	}))
+	return s
+}
+
+func (s *outboxServer) setOnline(online bool) {
+	s.mu.Lock()
+	s.online = online
+	s.mu.Unlock()
+}
+
+func tempOutboxPath(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "pushbullet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "outbox.json")
+}
+
+func TestOutbox(t *testing.T) {
+	server := OutboxResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ob, err := OpenOutbox(pb, path, &OutboxOptions{RetryInterval: time.Hour})
+	assert.NoError(t, err)
+
+	_, err = ob.Push("first", "/pushes", n)
+	assert.NoError(t, err)
+	_, err = ob.Push("second", "/pushes", l)
+	assert.NoError(t, err)
+	_, err = ob.Push("first", "/pushes", n)
+	assert.NoError(t, err)
+	guid, err := ob.PushNote(d.Iden, n.Title, n.Body)
+	assert.NoError(t, err)
+	assert.Len(t, guid, 36)
+	assert.Equal(t, 3, ob.Len())
+	assert.Error(t, ob.Flush())
+	assert.NoError(t, ob.Close())
+
+	_, err = ob.Push("", "/pushes", n)
+	assert.Equal(t, ErrOutboxClosed, err)
+
+	server.setOnline(true)
+	ob, err = OpenOutbox(pb, path, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, ob.Flush())
+	assert.Equal(t, 0, ob.Len())
+	assert.Equal(t, []string{"first", "second", guid}, server.guids)
+
+	_, err = ob.Push("second", "/pushes", l)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, ob.Len())
+	assert.NoError(t, ob.Close())
+}
+
+func TestOutboxBackgroundDelivery(t *testing.T) {
+	server := OutboxResponseStub()
+	defer server.Close()
+	server.setOnline(true)
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ob, err := OpenOutbox(pb, path, nil)
+	assert.NoError(t, err)
+	defer ob.Close()
+	_, err = ob.Push("bg", "/pushes", n)
+	assert.NoError(t, err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ob.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 0, ob.Len())
+}
+
+func TestOutboxDrop(t *testing.T) {
+	var reqs []recordedRequest
+	errJSON, _ := json.Marshal(errorResponse{*e})
+	server := RequestResponseStub(&reqs, http.StatusBadRequest, string(errJSON))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	dropped := make(chan error, 1)
+	ob, err := OpenOutbox(pb, path, &OutboxOptions{
+		OnDrop: func(guid string, err error) { dropped <- err },
+	})
+	assert.NoError(t, err)
+	defer ob.Close()
+	_, err = ob.Push("bad", "/pushes", n)
+	assert.NoError(t, err)
+	rejected := *e
+	rejected.StatusCode = http.StatusBadRequest
+	assert.Equal(t, &rejected, <-dropped)
+	assert.NoError(t, ob.Flush())
+}
+
+func TestOutboxDropNonJSON(t *testing.T) {
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusBadRequest, "<html>Bad Request</html>")
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	dropped := make(chan error, 1)
+	ob, err := OpenOutbox(pb, path, &OutboxOptions{
+		OnDrop: func(guid string, err error) { dropped <- err },
+	})
+	assert.NoError(t, err)
+	defer ob.Close()
+	_, err = ob.Push("bad", "/pushes", n)
+	assert.NoError(t, err)
+	assert.Equal(t, &ErrResponse{Message: "400 Bad Request", StatusCode: http.StatusBadRequest}, <-dropped)
+	assert.NoError(t, ob.Flush())
+	assert.Equal(t, 0, ob.Len())
+}
+
+func TestOutboxServerError(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	ob, err := OpenOutbox(pb, path, &OutboxOptions{
+		OnDrop: func(guid string, err error) { t.Errorf("dropped %s: %v", guid, err) },
+	})
+	assert.NoError(t, err)
+	defer ob.Close()
+	_, err = ob.Push("retry", "/pushes", n)
+	assert.NoError(t, err)
+	assert.Equal(t, e, ob.Flush())
+	assert.Equal(t, 1, ob.Len())
+}
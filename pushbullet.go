@@ -5,7 +5,7 @@ Example client:
 	pb := pushbullet.New("YOUR_API_KEY")
 	devices, err := pb.Devices()
 	...
-	err = pb.PushNote(devices[0].Iden, "Hello!", "Hi from go-pushbullet!")
+	push, err := pb.PushNote(devices[0].Iden, "Hello!", "Hi from go-pushbullet!")
 
 The API is document at https://docs.pushbullet.com/http/ .  At the moment, it only supports querying devices and sending notifications.
 
@@ -17,9 +17,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // ErrDeviceNotFound is raised when device nickname is not found on pusbullet server
@@ -28,9 +32,20 @@ var ErrDeviceNotFound = errors.New("Device not found")
 // EndpointURL sets the default URL for the Pushbullet API
 var EndpointURL = "https://api.pushbullet.com/v2"
 
+// Conservative size limits for push fields, in characters. Longer values may
+// be cut off or rejected. Push validation rejects titles over MaxTitleLength.
+const (
+	MaxTitleLength = 250
+	MaxBodyLength  = 4000
+)
+
+// StreamURL sets the default URL for the Pushbullet realtime event stream
+var StreamURL = "wss://stream.pushbullet.com/websocket/"
+
 // Endpoint allows manipulation of pushbullet API endpoint for testing
 type Endpoint struct {
-	URL string
+	URL       string
+	StreamURL string
 }
 
 // A Client connects to PushBullet with an API Key.
@@ -38,37 +53,52 @@ type Client struct {
 	Key    string
 	Client *http.Client
 	Endpoint
+
+	// Retries is how often a request that failed with a network error or a
+	// server error is retried. Only reads, deletes and pushes are retried;
+	// pushes keep their guid so that a retry cannot notify twice. New sets
+	// it to 2.
+	Retries int
+
+	// SkipValidation disables the client-side validation of push payloads,
+	// leaving it to the server.
+	SkipValidation bool
+
+	mu            sync.Mutex
+	maxUploadSize int64 // cached from Me, 0 if not known yet
 }
 
 // New creates a new client with your personal API key.
 func New(apikey string) *Client {
-	endpoint := Endpoint{URL: EndpointURL}
-	return &Client{apikey, http.DefaultClient, endpoint}
+	endpoint := Endpoint{URL: EndpointURL, StreamURL: StreamURL}
+	return &Client{Key: apikey, Client: http.DefaultClient, Endpoint: endpoint, Retries: 2}
 }
 
 // NewWithClient creates a new client with your personal API key and the given http Client
 func NewWithClient(apikey string, client *http.Client) *Client {
-	endpoint := Endpoint{URL: EndpointURL}
-	return &Client{apikey, client, endpoint}
+	endpoint := Endpoint{URL: EndpointURL, StreamURL: StreamURL}
+	return &Client{Key: apikey, Client: client, Endpoint: endpoint, Retries: 2}
 }
 
 // A Device is a PushBullet device
 type Device struct {
-	Iden              string  `json:"iden"`
-	Active            bool    `json:"active"`
-	Created           float32 `json:"created"`
-	Modified          float32 `json:"modified"`
-	Icon              string  `json:"icon"`
-	Nickname          string  `json:"nickname"`
-	GeneratedNickname bool    `json:"generated_nickname"`
-	Manufacturer      string  `json:"manufacturer"`
-	Model             string  `json:"model"`
-	AppVersion        int     `json:"app_version"`
-	Fingerprint       string  `json:"fingerprint"`
-	KeyFingerprint    string  `json:"key_fingerprint"`
-	PushToken         string  `json:"push_token"`
-	HasSms            bool    `json:"has_sms"`
-	Client            *Client `json:"-"`
+	Iden              string    `json:"iden"`
+	Active            bool      `json:"active"`
+	Created           Timestamp `json:"created"`
+	Modified          Timestamp `json:"modified"`
+	Icon              string    `json:"icon"`
+	Nickname          string    `json:"nickname"`
+	GeneratedNickname bool      `json:"generated_nickname"`
+	Manufacturer      string    `json:"manufacturer"`
+	Model             string    `json:"model"`
+	AppVersion        int       `json:"app_version"`
+	Fingerprint       string    `json:"fingerprint"`
+	KeyFingerprint    string    `json:"key_fingerprint"`
+	PushToken         string    `json:"push_token"`
+	HasSms            bool      `json:"has_sms"`
+	Pushable          bool      `json:"pushable"`
+	Shared            bool      `json:"-"`
+	Client            *Client   `json:"-"`
 }
 
 // ErrResponse is an error returned by the PushBullet API
@@ -76,12 +106,40 @@ type ErrResponse struct {
 	Type    string `json:"type"`
 	Message string `json:"message"`
 	Cat     string `json:"cat"`
+
+	// StatusCode is the HTTP status of the response.
+	StatusCode int `json:"-"`
 }
 
 func (e *ErrResponse) Error() string {
 	return e.Message
 }
 
+// Temporary reports whether the request may succeed when retried, that is
+// whether the server failed rather than rejected the request.
+func (e *ErrResponse) Temporary() bool {
+	return e.StatusCode >= 500
+}
+
+// RateLimitError is returned when PushBullet rejects a request because the
+// account has used up its request quota. Reset is the time at which the
+// quota is refilled, or the zero time if the server did not say.
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return "Rate limit exceeded"
+}
+
+func newRateLimitError(resp *http.Response) *RateLimitError {
+	var e RateLimitError
+	if sec, err := strconv.ParseInt(resp.Header.Get("X-Ratelimit-Reset"), 10, 64); err == nil {
+		e.Reset = time.Unix(sec, 0)
+	}
+	return &e
+}
+
 type errorResponse struct {
 	ErrResponse `json:"error"`
 }
@@ -95,59 +153,93 @@ type subscriptionResponse struct {
 	Subscriptions []*Subscription
 }
 
-func (c *Client) buildRequest(object string, data interface{}) *http.Request {
-	r, err := http.NewRequest("GET", c.Endpoint.URL+object, nil)
+// buildRequest creates an API request for path. The query is encoded into the
+// URL and data, if not nil, is sent as a JSON body which GetBody can replay
+// for retries and redirects.
+func (c *Client) buildRequest(method, path string, query url.Values, data interface{}) (*http.Request, error) {
+	u, err := url.Parse(c.Endpoint.URL + path)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+	if len(query) > 0 {
+		q := u.Query()
+		for k, vs := range query {
+			q[k] = append(q[k], vs...)
+		}
+		u.RawQuery = q.Encode()
 	}
 
-	// appengine sdk requires us to set the auth header by hand
-	u := url.UserPassword(c.Key, "")
-	r.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(u.String())))
+	var body io.Reader
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	r, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
 
+	// appengine sdk requires us to set the auth header by hand
+	ui := url.UserPassword(c.Key, "")
+	r.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(ui.String())))
 	if data != nil {
-		r.Method = "POST"
 		r.Header.Set("Content-Type", "application/json")
-		var b bytes.Buffer
-		enc := json.NewEncoder(&b)
-		enc.Encode(data)
-		r.Body = ioutil.NopCloser(&b)
 	}
-
-	return r
+	return r, nil
 }
 
 // Devices fetches a list of devices from PushBullet.
 func (c *Client) Devices() ([]*Device, error) {
-	req := c.buildRequest("/devices", nil)
-	resp, err := c.Client.Do(req)
+	devResp, err := get[deviceResponse](c, "/devices", nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		var errjson errorResponse
-		dec := json.NewDecoder(resp.Body)
-		err = dec.Decode(&errjson)
-		if err == nil {
-			return nil, &errjson.ErrResponse
-		}
 
-		return nil, errors.New(resp.Status)
+	for _, dev := range devResp.SharedDevices {
+		dev.Shared = true
 	}
-
-	var devResp deviceResponse
-	dec := json.NewDecoder(resp.Body)
-	err = dec.Decode(&devResp)
-	if err != nil {
-		return nil, err
+	devices := append(devResp.Devices, devResp.SharedDevices...)
+	for i := range devices {
+		devices[i].Client = c
 	}
+	return devices, nil
+}
 
-	for i := range devResp.Devices {
-		devResp.Devices[i].Client = c
+// A DeviceFilter reports whether a device should be kept by FilterDevices.
+type DeviceFilter func(d *Device) bool
+
+// ActiveDevices keeps devices that have not been deleted.
+func ActiveDevices(d *Device) bool {
+	return d.Active
+}
+
+// OwnedDevices keeps devices that belong to the user rather than being
+// shared with them.
+func OwnedDevices(d *Device) bool {
+	return !d.Shared
+}
+
+// PushableDevices keeps active devices that can receive pushes.
+func PushableDevices(d *Device) bool {
+	return d.Active && d.Pushable
+}
+
+// FilterDevices returns the devices accepted by all of the given filters.
+func FilterDevices(devices []*Device, filters ...DeviceFilter) []*Device {
+	var kept []*Device
+outer:
+	for _, d := range devices {
+		for _, keep := range filters {
+			if !keep(d) {
+				continue outer
+			}
+		}
+		kept = append(kept, d)
 	}
-	devices := append(devResp.Devices, devResp.SharedDevices...)
-	return devices, nil
+	return kept
 }
 
 // Device fetches an device with a given nickname from PushBullet.
@@ -159,20 +251,100 @@ func (c *Client) Device(nickname string) (*Device, error) {
 
 	for i := range devices {
 		if devices[i].Nickname == nickname {
-			devices[i].Client = c
 			return devices[i], nil
 		}
 	}
 	return nil, ErrDeviceNotFound
 }
 
+// AmbiguousDeviceError is returned when a device query matches more than one
+// device and none of them is a better match than the others.
+type AmbiguousDeviceError struct {
+	Query      string
+	Candidates []*Device
+}
+
+func (e *AmbiguousDeviceError) Error() string {
+	names := make([]string, len(e.Candidates))
+	for i, d := range e.Candidates {
+		names[i] = d.Name()
+	}
+	return "Device \"" + e.Query + "\" is ambiguous: " + strings.Join(names, ", ")
+}
+
+// Name returns the nickname of the device, falling back to its model.
+func (d *Device) Name() string {
+	if d.Nickname != "" {
+		return d.Nickname
+	}
+	return d.Model
+}
+
+// ResolveDevice fetches the device matching query from PushBullet. Deleted
+// devices are ignored. See FindDevice for the matching rules.
+func (c *Client) ResolveDevice(query string) (*Device, error) {
+	devices, err := c.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	return FindDevice(FilterDevices(devices, ActiveDevices), query)
+}
+
+// FindDevice picks the device matching query out of devices. The query is
+// compared, in order, against the iden, the exact nickname, the nickname
+// ignoring case, the model ignoring case, a prefix of the nickname or model
+// and finally a substring of the nickname or model. The first rule that
+// matches a single device wins. If a rule matches several devices an
+// *AmbiguousDeviceError listing them is returned; if no rule matches at all
+// the error is ErrDeviceNotFound.
+func FindDevice(devices []*Device, query string) (*Device, error) {
+	if query == "" {
+		return nil, ErrDeviceNotFound
+	}
+
+	lower := strings.ToLower(query)
+	rules := []func(d *Device) bool{
+		func(d *Device) bool { return d.Iden == query },
+		func(d *Device) bool { return d.Nickname == query },
+		func(d *Device) bool { return strings.EqualFold(d.Nickname, query) },
+		func(d *Device) bool { return strings.EqualFold(d.Model, query) },
+		func(d *Device) bool {
+			return strings.HasPrefix(strings.ToLower(d.Nickname), lower) ||
+				strings.HasPrefix(strings.ToLower(d.Model), lower)
+		},
+		func(d *Device) bool {
+			return strings.Contains(strings.ToLower(d.Nickname), lower) ||
+				strings.Contains(strings.ToLower(d.Model), lower)
+		},
+	}
+
+	for _, match := range rules {
+		var found []*Device
+		for _, d := range devices {
+			if match(d) {
+				found = append(found, d)
+			}
+		}
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			return nil, &AmbiguousDeviceError{Query: query, Candidates: found}
+		}
+	}
+	return nil, ErrDeviceNotFound
+}
+
 // PushNote sends a note to the specific device with the given title and body
-func (d *Device) PushNote(title, body string) error {
+func (d *Device) PushNote(title, body string) (*Push, error) {
 	return d.Client.PushNote(d.Iden, title, body)
 }
 
 // PushLink sends a link to the specific device with the given title and url
-func (d *Device) PushLink(title, u, body string) error {
+func (d *Device) PushLink(title, u, body string) (*Push, error) {
 	return d.Client.PushLink(d.Iden, title, u, body)
 }
 
@@ -184,79 +356,89 @@ func (d *Device) PushSMS(deviceIden, phoneNumber, message string) error {
 // User represents the User object for pushbullet
 type User struct {
 	Iden            string      `json:"iden"`
+	Active          bool        `json:"active"`
 	Email           string      `json:"email"`
 	EmailNormalized string      `json:"email_normalized"`
-	Created         float64     `json:"created"`
-	Modified        float64     `json:"modified"`
+	Created         Timestamp   `json:"created"`
+	Modified        Timestamp   `json:"modified"`
 	Name            string      `json:"name"`
 	ImageUrl        string      `json:"image_url"`
-	Preferences     interface{} `json:"preferences"`
+	MaxUploadSize   int64       `json:"max_upload_size"`
+	Pro             bool        `json:"pro"`
+	ReferredCount   int         `json:"referred_count"`
+	ReferrerIden    string      `json:"referrer_iden,omitempty"`
+	Preferences     Preferences `json:"preferences"`
 }
 
 // Me returns the user object for the pushbullet user
 func (c *Client) Me() (*User, error) {
-	req := c.buildRequest("/users/me", nil)
-	resp, err := c.Client.Do(req)
+	user, err := get[User](c, "/users/me", nil)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		var errjson errorResponse
-		dec := json.NewDecoder(resp.Body)
-		err = dec.Decode(&errjson)
-		if err == nil {
-			return nil, &errjson.ErrResponse
-		}
-
-		return nil, errors.New(resp.Status)
-	}
+	c.setMaxUploadSize(user.MaxUploadSize)
+	return user, nil
+}
 
-	var userResponse User
-	dec := json.NewDecoder(resp.Body)
-	err = dec.Decode(&userResponse)
-	if err != nil {
+// Push pushes the data to a specific device registered with PushBullet.  The
+// 'data' parameter is marshaled to JSON and sent as the request body.  The
+// push created by the server is returned.  Most users should call one of
+// PushNote, PushLink, PushFile, PushAddress, or PushList.
+//
+// If data is a Validator it is validated first, and a *ValidationError is
+// returned without sending anything if it is invalid. Note, Link, File,
+// Address and List payloads without a GUID are given a random one, so that
+// PushBullet can recognize a retried push.
+func (c *Client) Push(endPoint string, data interface{}) (*Push, error) {
+	if err := c.validate(data); err != nil {
 		return nil, err
 	}
-	return &userResponse, nil
+	return post[Push](c, endPoint, ensureGUID(data))
 }
 
-// Push pushes the data to a specific device registered with PushBullet.  The
-// 'data' parameter is marshaled to JSON and sent as the request body.  Most
-// users should call one of PusNote, PushLink, PushAddress, or PushList.
-func (c *Client) Push(endPoint string, data interface{}) error {
-	req := c.buildRequest(endPoint, data)
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return err
+// checkResponse turns an unsuccessful API response into an error. Responses
+// without a JSON error, such as error pages of proxies, become an
+// *ErrResponse with the status as message.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return newRateLimitError(resp)
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
 		var errResponse errorResponse
 		dec := json.NewDecoder(resp.Body)
-		err = dec.Decode(&errResponse)
+		err := dec.Decode(&errResponse)
 		if err == nil {
+			errResponse.StatusCode = resp.StatusCode
 			return &errResponse.ErrResponse
 		}
 
-		return errors.New(resp.Status)
+		return &ErrResponse{Message: resp.Status, StatusCode: resp.StatusCode}
 	}
-
 	return nil
 }
 
+// DismissPush marks the push with the given iden as dismissed, removing its
+// notification from the user's devices.
+func (c *Client) DismissPush(iden string) error {
+	data := struct {
+		Dismissed bool `json:"dismissed"`
+	}{true}
+	return c.exec("POST", "/pushes/"+url.PathEscape(iden), nil, data, nil)
+}
+
 // Note exposes the required and optional fields of the Pushbullet push type=note
 type Note struct {
 	Iden  string `json:"device_iden,omitempty"`
 	Tag   string `json:"channel_tag,omitempty"`
+	Email string `json:"email,omitempty"`
 	Type  string `json:"type"`
+	GUID  string `json:"guid,omitempty"`
 	Title string `json:"title"`
 	Body  string `json:"body"`
 }
 
 // PushNote pushes a note with title and body to a specific PushBullet device.
-func (c *Client) PushNote(iden string, title, body string) error {
+func (c *Client) PushNote(iden string, title, body string) (*Push, error) {
 	data := Note{
 		Iden:  iden,
 		Type:  "note",
@@ -267,7 +449,7 @@ func (c *Client) PushNote(iden string, title, body string) error {
 }
 
 // PushNoteToChannel pushes a note with title and body to a specific PushBullet channel.
-func (c *Client) PushNoteToChannel(tag string, title, body string) error {
+func (c *Client) PushNoteToChannel(tag string, title, body string) (*Push, error) {
 	data := Note{
 		Tag:   tag,
 		Type:  "note",
@@ -281,14 +463,16 @@ func (c *Client) PushNoteToChannel(tag string, title, body string) error {
 type Link struct {
 	Iden  string `json:"device_iden,omitempty"`
 	Tag   string `json:"channel_tag,omitempty"`
+	Email string `json:"email,omitempty"`
 	Type  string `json:"type"`
+	GUID  string `json:"guid,omitempty"`
 	Title string `json:"title"`
 	URL   string `json:"url"`
 	Body  string `json:"body,omitempty"`
 }
 
 // PushLink pushes a link with a title and url to a specific PushBullet device.
-func (c *Client) PushLink(iden, title, u, body string) error {
+func (c *Client) PushLink(iden, title, u, body string) (*Push, error) {
 	data := Link{
 		Iden:  iden,
 		Type:  "link",
@@ -300,7 +484,7 @@ func (c *Client) PushLink(iden, title, u, body string) error {
 }
 
 // PushLinkToChannel pushes a link with a title and url to a specific PushBullet device.
-func (c *Client) PushLinkToChannel(tag, title, u, body string) error {
+func (c *Client) PushLinkToChannel(tag, title, u, body string) (*Push, error) {
 	data := Link{
 		Tag:   tag,
 		Type:  "link",
@@ -340,18 +524,21 @@ func (c *Client) PushSMS(userIden, deviceIden, phoneNumber, message string) erro
 			Message:          message,
 		},
 	}
-	return c.Push("/ephemerals", data)
+	if err := c.validate(data); err != nil {
+		return err
+	}
+	return c.exec("POST", "/ephemerals", nil, data, nil)
 }
 
 // Subscription object allows interaction with pushbullet channels
 type Subscription struct {
-	Iden     string   `json:"iden"`
-	Active   bool     `json:"active"`
-	Created  float32  `json:"created"`
-	Modified float32  `json:"modified"`
-	Muted    string   `json:"muted"`
-	Channel  *Channel `json:"channel"`
-	Client   *Client  `json:"-"`
+	Iden     string    `json:"iden"`
+	Active   bool      `json:"active"`
+	Created  Timestamp `json:"created"`
+	Modified Timestamp `json:"modified"`
+	Muted    string    `json:"muted"`
+	Channel  *Channel  `json:"channel"`
+	Client   *Client   `json:"-"`
 }
 
 // Channel object contains specific information about the pushbullet Channel
@@ -365,26 +552,7 @@ type Channel struct {
 }
 
 func (c *Client) Subscriptions() ([]*Subscription, error) {
-	req := c.buildRequest("/subscriptions", nil)
-	resp, err := c.Client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		var errjson errorResponse
-		dec := json.NewDecoder(resp.Body)
-		err = dec.Decode(&errjson)
-		if err == nil {
-			return nil, &errjson.ErrResponse
-		}
-
-		return nil, errors.New(resp.Status)
-	}
-
-	var subResp subscriptionResponse
-	dec := json.NewDecoder(resp.Body)
-	err = dec.Decode(&subResp)
+	subResp, err := get[subscriptionResponse](c, "/subscriptions", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -392,8 +560,7 @@ func (c *Client) Subscriptions() ([]*Subscription, error) {
 	for i := range subResp.Subscriptions {
 		subResp.Subscriptions[i].Client = c
 	}
-	subscriptions := append(subResp.Subscriptions)
-	return subscriptions, nil
+	return subResp.Subscriptions, nil
 }
 
 // Subscription fetches an subscription with a given channel tag from PushBullet.
@@ -413,11 +580,11 @@ func (c *Client) Subscription(tag string) (*Subscription, error) {
 }
 
 // PushNote sends a note to the specific Channel with the given title and body
-func (s *Subscription) PushNote(title, body string) error {
+func (s *Subscription) PushNote(title, body string) (*Push, error) {
 	return s.Client.PushNoteToChannel(s.Channel.Tag, title, body)
 }
 
 // PushNote sends a link to the specific Channel with the given title, url and body
-func (s *Subscription) PushLink(title, u, body string) error {
+func (s *Subscription) PushLink(title, u, body string) (*Push, error) {
 	return s.Client.PushLinkToChannel(s.Channel.Tag, title, u, body)
 }
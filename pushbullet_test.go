@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,16 +14,25 @@ import (
 var d = &Device{
 	Active:            true,
 	AppVersion:        8623,
-	Created:           1.412047948579029e+09,
+	Created:           ts("1412047948.579029"),
 	Iden:              "ujpah72o0sjAoRtnM0jc",
 	Manufacturer:      "Apple",
 	Model:             "iPhone 5s (GSM)",
-	Modified:          1.412047948579031e+09,
+	Modified:          ts("1412047948.579031"),
 	Nickname:          "Elon Musk's iPhone",
 	GeneratedNickname: true,
 	PushToken:         "production:f73be0ee7877c8c7fa69b1468cde764f",
 }
 
+var sd = &Device{
+	Active:   true,
+	Iden:     "ujxPklLhvyKsjAvkMyTVh6",
+	Model:    "Nexus 6",
+	Nickname: "Family Tablet",
+	Pushable: true,
+	Shared:   true,
+}
+
 var n = &Note{
 	Type:  "note",
 	Title: "Space Travel Ideas",
@@ -33,16 +43,27 @@ var e = &ErrResponse{
 	Type:    "invalid_request",
 	Message: "The resource could not be found.",
 	Cat:     "~(=^‥^)",
+
+	StatusCode: http.StatusInternalServerError,
 }
 
 var m = &User{
-	Created:         1.381092887398433e+09,
+	Active:          true,
+	Created:         ts("1381092887.398433"),
 	Email:           "elon@teslamotors.com",
 	EmailNormalized: "elon@teslamotors.com",
 	Iden:            "ujpah72o0",
 	ImageUrl:        "https://static.pushbullet.com/missing-image/55a7dc-45",
-	Modified:        1.441054560741007e+09,
+	Modified:        ts("1441054560.741007"),
 	Name:            "Elon Musk",
+	MaxUploadSize:   26214400,
+	Pro:             true,
+	ReferredCount:   2,
+	Preferences: Preferences{
+		Onboarding: &Onboarding{App: true, Friends: false, Extension: true},
+		Social:     true,
+		Other:      map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)},
+	},
 }
 
 var l = &Link{
@@ -73,9 +94,9 @@ var c = &Channel{
 var sub = &Subscription{
 	Active:   true,
 	Channel:  c,
-	Created:  1.412047948579029e+09,
+	Created:  ts("1412047948.579029"),
 	Iden:     "ujpah72o0sjAoRtnM0jc",
-	Modified: 1.412047948579031e+09,
+	Modified: ts("1412047948.579031"),
 }
 
 var k = "API_KEY"
@@ -86,13 +107,14 @@ func PushbulletResponseStub() *httptest.Server {
 		switch r.RequestURI {
 		case "/devices":
 			d, _ := json.Marshal(d)
-			resp = `{ "devices": [` + string(d) + `] }`
+			sd, _ := json.Marshal(sd)
+			resp = `{ "devices": [` + string(d) + `], "shared_devices": [` + string(sd) + `] }`
 		case "/users/me":
 			m, _ := json.Marshal(m)
 			resp = string(m)
 		case "/pushes":
-			n, _ := json.Marshal(n)
-			resp = string(n)
+			p, _ := json.Marshal(p)
+			resp = string(p)
 		case "/ephemerals":
 			s, _ := json.Marshal(s)
 			resp = string(s)
@@ -140,25 +162,99 @@ func TestError(t *testing.T) {
 
 func TestBuildRequest(t *testing.T) {
 	pb := New(k)
-	req := pb.buildRequest("/pushes", n)
+	req, err := pb.buildRequest("POST", "/pushes", nil, n)
+	assert.NoError(t, err)
 	buf := new(bytes.Buffer)
 	buf.ReadFrom(req.Body)
 	var note Note
 	json.Unmarshal(buf.Bytes(), &note)
 	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, n, &note)
+
+	body, err := req.GetBody()
+	assert.NoError(t, err)
+	buf.Reset()
+	buf.ReadFrom(body)
+	note = Note{}
+	json.Unmarshal(buf.Bytes(), &note)
 	assert.Equal(t, n, &note)
 }
 
+func TestBuildRequestMethodAndQuery(t *testing.T) {
+	pb := New(k)
+	req, err := pb.buildRequest("DELETE", "/pushes/abc", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "DELETE", req.Method)
+	assert.Nil(t, req.Body)
+	assert.Equal(t, "", req.Header.Get("Content-Type"))
+
+	req, err = pb.buildRequest("POST", "/pushes", nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "POST", req.Method)
+	assert.Nil(t, req.Body)
+
+	req, err = pb.buildRequest("GET", "/pushes?active=true", url.Values{"cursor": {"a&b=c"}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "active=true&cursor=a%26b%3Dc", req.URL.RawQuery)
+	assert.Equal(t, "/v2/pushes", req.URL.Path)
+}
+
+func TestBuildRequestErrors(t *testing.T) {
+	pb := New(k)
+	pb.Endpoint.URL = "http://[::1"
+	_, err := pb.buildRequest("GET", "/pushes", nil, nil)
+	assert.Error(t, err)
+
+	pb = New(k)
+	_, err = pb.buildRequest("POST", "/pushes", nil, func() {})
+	assert.Error(t, err)
+}
+
 func TestDevices(t *testing.T) {
 	server := PushbulletResponseStub()
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
 	d.Client = pb
+	sd.Client = pb
 	devs, err := pb.Devices()
 	assert.NoError(t, err)
-	assert.Len(t, devs, 1)
+	assert.Len(t, devs, 2)
 	assert.Equal(t, d, devs[0])
+	assert.Equal(t, sd, devs[1])
+}
+
+func TestFilterDevices(t *testing.T) {
+	devs := []*Device{
+		{Iden: "a", Active: true, Pushable: true},
+		{Iden: "b", Active: true, Pushable: true, Shared: true},
+		{Iden: "c", Active: false, Pushable: true},
+		{Iden: "d", Active: true},
+	}
+	idens := func(devs []*Device) []string {
+		var s []string
+		for _, d := range devs {
+			s = append(s, d.Iden)
+		}
+		return s
+	}
+	assert.Equal(t, []string{"a", "b", "d"}, idens(FilterDevices(devs, ActiveDevices)))
+	assert.Equal(t, []string{"a", "c", "d"}, idens(FilterDevices(devs, OwnedDevices)))
+	assert.Equal(t, []string{"a"}, idens(FilterDevices(devs, OwnedDevices, PushableDevices)))
+	assert.Len(t, FilterDevices(devs), 4)
+}
+
+func TestSharedDevicePushNote(t *testing.T) {
+	server := PushbulletResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	dev, err := pb.Device(sd.Nickname)
+	assert.NoError(t, err)
+	assert.True(t, dev.Shared)
+	_, err = dev.PushNote(n.Title, n.Body)
+	assert.NoError(t, err)
 }
 
 func TestDeviceWithNickname(t *testing.T) {
@@ -188,7 +284,7 @@ func TestDevicePushNote(t *testing.T) {
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
 	dev, _ := pb.Device(d.Nickname)
-	err := dev.PushNote(n.Title, n.Body)
+	_, err := dev.PushNote(n.Title, n.Body)
 	assert.NoError(t, err)
 }
 
@@ -198,7 +294,7 @@ func TestDevicePushLink(t *testing.T) {
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
 	dev, _ := pb.Device(d.Nickname)
-	err := dev.PushLink(l.Title, l.URL, l.Body)
+	_, err := dev.PushLink(l.Title, l.URL, l.Body)
 	assert.NoError(t, err)
 }
 
@@ -269,8 +365,9 @@ func TestPush(t *testing.T) {
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
-	err := pb.Push("/pushes", n)
+	push, err := pb.Push("/pushes", n)
 	assert.NoError(t, err)
+	assert.Equal(t, p, push)
 }
 
 func TestPushError(t *testing.T) {
@@ -278,7 +375,8 @@ func TestPushError(t *testing.T) {
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
-	err := pb.Push("/pushes", n)
+	push, err := pb.Push("/pushes", n)
+	assert.Nil(t, push)
 	assert.Error(t, err)
 	assert.Equal(t, "500 Internal Server Error", err.Error())
 }
@@ -288,7 +386,8 @@ func TestPushJSONError(t *testing.T) {
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
-	err := pb.Push("/pushes", n)
+	push, err := pb.Push("/pushes", n)
+	assert.Nil(t, push)
 	assert.Error(t, err)
 	assert.Equal(t, e, err)
 }
@@ -298,7 +397,7 @@ func TestPushLink(t *testing.T) {
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
-	err := pb.PushLink(m.Iden, l.Title, l.URL, l.Body)
+	_, err := pb.PushLink(m.Iden, l.Title, l.URL, l.Body)
 	assert.NoError(t, err)
 }
 
@@ -307,8 +406,9 @@ func TestPushNote(t *testing.T) {
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
-	err := pb.PushNote(m.Iden, n.Title, n.Body)
+	push, err := pb.PushNote(m.Iden, n.Title, n.Body)
 	assert.NoError(t, err)
+	assert.Equal(t, p.Iden, push.Iden)
 }
 
 func TestPushLinkToChannel(t *testing.T) {
@@ -316,7 +416,7 @@ func TestPushLinkToChannel(t *testing.T) {
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
-	err := pb.PushLinkToChannel(sub.Channel.Tag, l.Title, l.URL, l.Body)
+	_, err := pb.PushLinkToChannel(sub.Channel.Tag, l.Title, l.URL, l.Body)
 	assert.NoError(t, err)
 }
 
@@ -325,7 +425,7 @@ func TestPushNoteToChannel(t *testing.T) {
 	defer server.Close()
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
-	err := pb.PushNoteToChannel(sub.Channel.Tag, n.Title, n.Body)
+	_, err := pb.PushNoteToChannel(sub.Channel.Tag, n.Title, n.Body)
 	assert.NoError(t, err)
 }
 
@@ -399,7 +499,7 @@ func TestSubscriptionPushNote(t *testing.T) {
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
 	sub, _ := pb.Subscription(sub.Channel.Tag)
-	err := sub.PushNote(n.Title, n.Body)
+	_, err := sub.PushNote(n.Title, n.Body)
 	assert.NoError(t, err)
 }
 
@@ -409,6 +509,81 @@ func TestSubscriptionPushLink(t *testing.T) {
 	pb := New(k)
 	pb.Endpoint.URL = server.URL
 	sub, _ := pb.Subscription(sub.Channel.Tag)
-	err := sub.PushLink(l.Title, l.URL, l.Body)
+	_, err := sub.PushLink(l.Title, l.URL, l.Body)
+	assert.NoError(t, err)
+}
+
+func TestFindDevice(t *testing.T) {
+	devs := []*Device{
+		{Iden: "a1", Nickname: "Pixel 7 Pro", Model: "Pixel 7 Pro"},
+		{Iden: "b2", Nickname: "Work Laptop", Model: "chrome"},
+		{Iden: "c3", Nickname: "Pixel", Model: "Pixel 4a"},
+		{Iden: "d4", Nickname: "", Model: "iPad Air"},
+	}
+	cases := map[string]string{
+		"b2":          "b2",
+		"Pixel":       "c3",
+		"work laptop": "b2",
+		"CHROME":      "b2",
+		"ipad":        "d4",
+		"laptop":      "b2",
+	}
+	for query, iden := range cases {
+		dev, err := FindDevice(devs, query)
+		assert.NoError(t, err, query)
+		if assert.NotNil(t, dev, query) {
+			assert.Equal(t, iden, dev.Iden, query)
+		}
+	}
+
+	_, err := FindDevice(devs, "tablet")
+	assert.Equal(t, ErrDeviceNotFound, err)
+	_, err = FindDevice(devs, "")
+	assert.Equal(t, ErrDeviceNotFound, err)
+}
+
+func TestFindDeviceAmbiguous(t *testing.T) {
+	devs := []*Device{
+		{Iden: "a1", Nickname: "Pixel 7 Pro"},
+		{Iden: "b2", Nickname: "Pixel 6"},
+	}
+	_, err := FindDevice(devs, "pixel")
+	if assert.IsType(t, &AmbiguousDeviceError{}, err) {
+		amb := err.(*AmbiguousDeviceError)
+		assert.Len(t, amb.Candidates, 2)
+		assert.Equal(t, `Device "pixel" is ambiguous: Pixel 7 Pro, Pixel 6`, err.Error())
+	}
+}
+
+func TestResolveDevice(t *testing.T) {
+	server := PushbulletResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	dev, err := pb.ResolveDevice("elon")
+	assert.NoError(t, err)
+	assert.Equal(t, d.Iden, dev.Iden)
+	assert.Equal(t, pb, dev.Client)
+}
+
+func TestResolveDeviceIgnoresDeleted(t *testing.T) {
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, `{"devices": [
+		{"iden": "old", "active": false, "nickname": "Pixel 6"},
+		{"iden": "new", "active": true, "nickname": "Pixel 7"}
+	]}`)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	dev, err := pb.ResolveDevice("pixel")
 	assert.NoError(t, err)
+	assert.Equal(t, "new", dev.Iden)
+	_, err = pb.ResolveDevice("old")
+	assert.Equal(t, ErrDeviceNotFound, err)
+}
+
+func init() {
+	// Don't wait between retries of failed requests in tests.
+	retryBackoff = 0
 }
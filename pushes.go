@@ -0,0 +1,80 @@
+package pushbullet
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// A Push is a push as stored by PushBullet.
+type Push struct {
+	Iden                    string     `json:"iden"`
+	Active                  bool       `json:"active"`
+	Created                 Timestamp  `json:"created"`
+	Modified                Timestamp  `json:"modified"`
+	Type                    string     `json:"type"`
+	Dismissed               bool       `json:"dismissed"`
+	GUID                    string     `json:"guid,omitempty"`
+	Direction               string     `json:"direction"`
+	SenderIden              string     `json:"sender_iden"`
+	SenderEmail             string     `json:"sender_email"`
+	SenderEmailNormalized   string     `json:"sender_email_normalized"`
+	SenderName              string     `json:"sender_name"`
+	ReceiverIden            string     `json:"receiver_iden"`
+	ReceiverEmail           string     `json:"receiver_email"`
+	ReceiverEmailNormalized string     `json:"receiver_email_normalized"`
+	TargetDeviceIden        string     `json:"target_device_iden,omitempty"`
+	SourceDeviceIden        string     `json:"source_device_iden,omitempty"`
+	ClientIden              string     `json:"client_iden,omitempty"`
+	ChannelIden             string     `json:"channel_iden,omitempty"`
+	Title                   string     `json:"title,omitempty"`
+	Body                    string     `json:"body,omitempty"`
+	URL                     string     `json:"url,omitempty"`
+	Name                    string     `json:"name,omitempty"`
+	Address                 string     `json:"address,omitempty"`
+	Items                   []ListItem `json:"items,omitempty"`
+	FileName                string     `json:"file_name,omitempty"`
+	FileType                string     `json:"file_type,omitempty"`
+	FileURL                 string     `json:"file_url,omitempty"`
+	ImageURL                string     `json:"image_url,omitempty"`
+	ImageWidth              int        `json:"image_width,omitempty"`
+	ImageHeight             int        `json:"image_height,omitempty"`
+}
+
+type pushResponse struct {
+	Pushes []*Push
+	Cursor string `json:"cursor"`
+}
+
+// Pushes fetches the most recent active pushes from PushBullet, newest
+// first. At most limit pushes are returned; if limit is 0 the server's
+// default applies.
+func (c *Client) Pushes(limit int) ([]*Push, error) {
+	q := url.Values{"active": {"true"}}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+
+	pushResp, err := get[pushResponse](c, "/pushes", q)
+	if err != nil {
+		return nil, err
+	}
+	return pushResp.Pushes, nil
+}
+
+// PushesSince fetches all pushes modified after the given time, including
+// deleted ones.
+func (c *Client) PushesSince(modifiedAfter time.Time) ([]*Push, error) {
+	q := url.Values{"modified_after": {formatEpoch(modifiedAfter)}}
+
+	pushResp, err := get[pushResponse](c, "/pushes", q)
+	if err != nil {
+		return nil, err
+	}
+	return pushResp.Pushes, nil
+}
+
+// DeletePush deletes the push with the given iden.
+func (c *Client) DeletePush(iden string) error {
+	return c.exec("DELETE", "/pushes/"+url.PathEscape(iden), nil, nil, nil)
+}
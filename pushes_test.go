@@ -0,0 +1,87 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var p = &Push{
+	Iden:      "ujpah72o0sjAoRtnM0jc",
+	Active:    true,
+	Created:   ts("1412047948.579029"),
+	Modified:  ts("1412047948.579031"),
+	Type:      "note",
+	Direction: "self",
+	Title:     "Space Travel Ideas",
+	Body:      "Space Elevator, Mars Hyperloop, Space Model S (Model Space?)",
+}
+
+func PushesResponseStub(methods *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*methods = append(*methods, r.Method+" "+r.RequestURI)
+		switch r.URL.Path {
+		case "/pushes":
+			p, _ := json.Marshal(p)
+			w.Write([]byte(`{"pushes": [` + string(p) + `], "cursor": ""}`))
+		case "/pushes/" + p.Iden:
+			w.Write([]byte(`{}`))
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+}
+
+func TestPushes(t *testing.T) {
+	var methods []string
+	server := PushesResponseStub(&methods)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	pushes, err := pb.Pushes(10)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Push{p}, pushes)
+	assert.Equal(t, []string{"GET /pushes?active=true&limit=10"}, methods)
+}
+
+func TestPushesSince(t *testing.T) {
+	var methods []string
+	server := PushesResponseStub(&methods)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	pushes, err := pb.PushesSince(p.Created.Time)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Push{p}, pushes)
+	assert.Equal(t, []string{"GET /pushes?modified_after=1412047948.579029"}, methods)
+}
+
+func TestDismissAndDeletePush(t *testing.T) {
+	var methods []string
+	server := PushesResponseStub(&methods)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	assert.NoError(t, pb.DismissPush(p.Iden))
+	assert.NoError(t, pb.DeletePush(p.Iden))
+	assert.Error(t, pb.DeletePush("missing"))
+	assert.Equal(t, []string{
+		"POST /pushes/" + p.Iden,
+		"DELETE /pushes/" + p.Iden,
+		"DELETE /pushes/missing",
+	}, methods)
+}
+
+func TestDeletePushJSONError(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	assert.Equal(t, e, pb.DeletePush(p.Iden))
+}
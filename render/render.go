@@ -0,0 +1,335 @@
+// Package render builds push titles, bodies and URLs from text/template
+// templates.
+/*
+
+A template set holds named push templates, each made of up to three
+templates for the title, body and URL:
+
+	set := render.New()
+	err := set.Parse(`
+	{{define "deploy.title"}}Deployed {{.Service}}{{end}}
+	{{define "deploy.body"}}{{.Version}} after {{humanize .Took}}{{end}}
+	`)
+	...
+	r, err := set.Render("deploy", data)
+	_, err = pb.PushNote(iden, r.Title, r.Body)
+
+Besides the standard template functions, templates can use truncate,
+truncateTitle, truncateBody, humanize, stripMarkdown, default, join, lower
+and upper. Rendered titles and bodies are always truncated to
+pushbullet.MaxTitleLength and pushbullet.MaxBodyLength.
+
+*/
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/xconstruct/go-pushbullet"
+)
+
+// ErrTemplateNotFound is returned when rendering a template that is not in
+// the set.
+var ErrTemplateNotFound = errors.New("Template not found")
+
+// Parts are the template names that make up a push template.
+var parts = []string{"title", "body", "url"}
+
+// Spec is the source of a push template.
+type Spec struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	URL   string `json:"url"`
+}
+
+// Rendered is the output of a push template.
+type Rendered struct {
+	Title string
+	Body  string
+	URL   string
+}
+
+// Note returns a note push of r for the target.
+func (r Rendered) Note(t pushbullet.Target) pushbullet.Note {
+	return pushbullet.Note{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "note",
+		Title: r.Title,
+		Body:  r.Body,
+	}
+}
+
+// Link returns a link push of r for the target.
+func (r Rendered) Link(t pushbullet.Target) pushbullet.Link {
+	return pushbullet.Link{
+		Iden:  t.Device,
+		Tag:   t.Channel,
+		Email: t.Email,
+		Type:  "link",
+		Title: r.Title,
+		URL:   r.URL,
+		Body:  r.Body,
+	}
+}
+
+// A Set is a collection of named push templates.
+type Set struct {
+	tmpl *template.Template
+}
+
+// New creates an empty template set.
+func New() *Set {
+	return &Set{tmpl: template.New("").Funcs(Funcs())}
+}
+
+// Add parses spec as the push template name.
+func (s *Set) Add(name string, spec Spec) error {
+	var b strings.Builder
+	for i, text := range []string{spec.Title, spec.Body, spec.URL} {
+		if text != "" {
+			fmt.Fprintf(&b, "{{define %q}}%s{{end}}", name+"."+parts[i], text)
+		}
+	}
+	return s.Parse(b.String())
+}
+
+// Parse parses text defining push templates. Each push template consists of
+// templates named NAME.title, NAME.body and NAME.url, of which at least the
+// title or the body must be defined. Parse validates the whole set and
+// leaves it unchanged if text is invalid.
+func (s *Set) Parse(text string) error {
+	t, err := s.tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	if _, err = t.Parse(text); err != nil {
+		return err
+	}
+	if err = validate(t); err != nil {
+		return err
+	}
+	s.tmpl = t
+	return nil
+}
+
+// ParseFiles parses the named files like Parse.
+func (s *Set) ParseFiles(filenames ...string) error {
+	var b strings.Builder
+	for _, name := range filenames {
+		text, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		b.Write(text)
+		b.WriteString("\n")
+	}
+	return s.Parse(b.String())
+}
+
+func validate(t *template.Template) error {
+	for _, tt := range t.Templates() {
+		name := tt.Name()
+		if name == "" {
+			continue
+		}
+		i := strings.LastIndex(name, ".")
+		if i <= 0 || !isPart(name[i+1:]) {
+			return errors.New("Invalid template name " + strconv.Quote(name) + ": must end in .title, .body or .url")
+		}
+		base := name[:i]
+		if t.Lookup(base+".title") == nil && t.Lookup(base+".body") == nil {
+			return errors.New("Template " + strconv.Quote(base) + " needs a title or a body")
+		}
+	}
+	return nil
+}
+
+func isPart(s string) bool {
+	for _, p := range parts {
+		if s == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names of the push templates in the set.
+func (s *Set) Names() []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, tt := range s.tmpl.Templates() {
+		i := strings.LastIndex(tt.Name(), ".")
+		if i <= 0 {
+			continue
+		}
+		if base := tt.Name()[:i]; !seen[base] {
+			seen[base] = true
+			names = append(names, base)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes the push template name with data.
+func (s *Set) Render(name string, data interface{}) (Rendered, error) {
+	var out [3]string
+	found := false
+	for i, part := range parts {
+		t := s.tmpl.Lookup(name + "." + part)
+		if t == nil {
+			continue
+		}
+		found = true
+		var b bytes.Buffer
+		if err := t.Execute(&b, data); err != nil {
+			return Rendered{}, err
+		}
+		out[i] = strings.TrimSpace(b.String())
+	}
+	if !found {
+		return Rendered{}, ErrTemplateNotFound
+	}
+	return Rendered{
+		Title: truncate(pushbullet.MaxTitleLength, out[0]),
+		Body:  truncate(pushbullet.MaxBodyLength, out[1]),
+		URL:   out[2],
+	}, nil
+}
+
+// Check renders every push template in the set with sample data and
+// returns the first error, to catch templates that do not fit the data
+// before they are used.
+func (s *Set) Check(sample interface{}) error {
+	for _, name := range s.Names() {
+		if _, err := s.Render(name, sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Funcs returns the helper functions available to push templates.
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"truncate":      truncate,
+		"truncateTitle": func(s string) string { return truncate(pushbullet.MaxTitleLength, s) },
+		"truncateBody":  func(s string) string { return truncate(pushbullet.MaxBodyLength, s) },
+		"humanize":      humanize,
+		"stripMarkdown": StripMarkdown,
+		"default":       defaultValue,
+		"join":          strings.Join,
+		"lower":         strings.ToLower,
+		"upper":         strings.ToUpper,
+	}
+}
+
+// truncate shortens s to at most n characters, marking the cut with an
+// ellipsis.
+func truncate(n int, s string) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:n])
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// humanize formats a duration like "3d 4h" or "2m 5s", keeping the two most
+// significant units. It accepts a time.Duration, a number of seconds or a
+// string understood by time.ParseDuration.
+func humanize(v interface{}) (string, error) {
+	var d time.Duration
+	switch v := v.(type) {
+	case time.Duration:
+		d = v
+	case int:
+		d = time.Duration(v) * time.Second
+	case int64:
+		d = time.Duration(v) * time.Second
+	case float64:
+		d = time.Duration(v * float64(time.Second))
+	case string:
+		var err error
+		if d, err = time.ParseDuration(v); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("humanize: unsupported type %T", v)
+	}
+
+	sign := ""
+	if d < 0 {
+		sign, d = "-", -d
+	}
+	if d < time.Second {
+		return sign + d.String(), nil
+	}
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+	first := 0
+	for d < units[first].size {
+		first++
+	}
+	var out []string
+	for _, u := range units[first:min(first+2, len(units))] {
+		if n := d / u.size; n > 0 {
+			out = append(out, strconv.FormatInt(int64(n), 10)+u.suffix)
+			d -= n * u.size
+		}
+	}
+	return sign + strings.Join(out, " "), nil
+}
+
+// defaultValue returns def if v is empty.
+func defaultValue(def, v interface{}) interface{} {
+	if v == nil || v == "" || v == 0 || v == false {
+		return def
+	}
+	return v
+}
+
+var markdownRules = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile("(?m)^```.*$\n?"), ""},
+	{regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`), "$1"},
+	{regexp.MustCompile(`\[([^\]]*)\]\(([^)]*)\)`), "$1 ($2)"},
+	{regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s+`), ""},
+	{regexp.MustCompile(`(?m)^\s{0,3}>\s?`), ""},
+	{regexp.MustCompile(`(?m)^\s{0,3}([-*_]\s*){3,}$`), ""},
+	{regexp.MustCompile("`([^`]*)`"), "$1"},
+	{regexp.MustCompile(`(\*\*|__)(.+?)(\*\*|__)`), "$2"},
+	{regexp.MustCompile(`(^|[^\w*])[*_]([^*_\s][^*_]*?)[*_]`), "$1$2"},
+	{regexp.MustCompile(`~~(.+?)~~`), "$1"},
+}
+
+// StripMarkdown removes Markdown formatting from s, leaving plain text.
+// Links are rendered as "text (url)".
+func StripMarkdown(s string) string {
+	for _, rule := range markdownRules {
+		s = rule.re.ReplaceAllString(s, rule.repl)
+	}
+	return s
+}
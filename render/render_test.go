@@ -0,0 +1,100 @@
+package render
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xconstruct/go-pushbullet"
+)
+
+type deploy struct {
+	Service string
+	Version string
+	Took    time.Duration
+	Notes   string
+	URL     string
+}
+
+func TestRender(t *testing.T) {
+	set := New()
+	err := set.Parse(`
+{{define "deploy.title"}}Deployed {{.Service}}{{end}}
+{{define "deploy.body"}}{{.Version}} after {{humanize .Took}}
+{{stripMarkdown .Notes}}{{end}}
+{{define "deploy.url"}}{{.URL}}{{end}}`)
+	assert.NoError(t, err)
+	assert.NoError(t, set.Add("short", Spec{Title: `{{truncate 8 .Service}}`}))
+	assert.Equal(t, []string{"deploy", "short"}, set.Names())
+
+	data := deploy{"billing", "v1.2.3", 90 * time.Second, "**Fixes** [bug](http://x/1)", "http://ci/42"}
+	r, err := set.Render("deploy", data)
+	assert.NoError(t, err)
+	assert.Equal(t, Rendered{
+		Title: "Deployed billing",
+		Body:  "v1.2.3 after 1m 30s\nFixes bug (http://x/1)",
+		URL:   "http://ci/42",
+	}, r)
+	assert.Equal(t, "http://ci/42", r.Link(pushbullet.DeviceTarget("phone")).URL)
+	assert.Equal(t, "phone", r.Note(pushbullet.DeviceTarget("phone")).Iden)
+
+	data.Service = "accounting-service"
+	r, err = set.Render("short", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "account…", r.Title)
+
+	_, err = set.Render("missing", data)
+	assert.Equal(t, ErrTemplateNotFound, err)
+	assert.NoError(t, set.Check(data))
+	assert.Error(t, set.Check(map[string]string{"Took": "forever"}))
+}
+
+func TestRenderTruncatesToLimits(t *testing.T) {
+	set := New()
+	assert.NoError(t, set.Add("long", Spec{Title: "{{.}}", Body: "{{.}}"}))
+	r, err := set.Render("long", strings.Repeat("x", 5000))
+	assert.NoError(t, err)
+	assert.Len(t, []rune(r.Title), pushbullet.MaxTitleLength)
+	assert.Len(t, []rune(r.Body), pushbullet.MaxBodyLength)
+}
+
+func TestParseInvalid(t *testing.T) {
+	set := New()
+	assert.Error(t, set.Parse(`{{define "a.title"}}{{.X}{{end}}`))
+	assert.Error(t, set.Parse(`{{define "a.subject"}}x{{end}}`))
+	assert.Error(t, set.Parse(`{{define "a.url"}}x{{end}}`))
+	assert.Error(t, set.Parse(`{{define "a.title"}}{{nosuchfunc .}}{{end}}`))
+	assert.Empty(t, set.Names())
+}
+
+func TestHumanize(t *testing.T) {
+	cases := map[interface{}]string{
+		90 * time.Second:                "1m 30s",
+		3*24*time.Hour + 5*time.Minute:  "3d",
+		26*time.Hour + 30*time.Minute:   "1d 2h",
+		45:                              "45s",
+		"2h5m":                          "2h 5m",
+		500 * time.Millisecond:          "500ms",
+		-(2*time.Hour + 59*time.Second): "-2h",
+		float64(3600):                   "1h",
+	}
+	for in, want := range cases {
+		got, err := humanize(in)
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "%v", in)
+	}
+	_, err := humanize(struct{}{})
+	assert.Error(t, err)
+}
+
+func TestStripMarkdown(t *testing.T) {
+	in := "# Title\n> quoted *text*\n- item with `code`\n```\nblock\n```\n![img](x.png) __bold__ ~~gone~~ snake_case_name"
+	want := "Title\nquoted text\n- item with code\nblock\nimg bold gone snake_case_name"
+	assert.Equal(t, want, StripMarkdown(in))
+}
+
+func TestDefault(t *testing.T) {
+	assert.Equal(t, "n/a", defaultValue("n/a", ""))
+	assert.Equal(t, "x", defaultValue("n/a", "x"))
+}
@@ -0,0 +1,105 @@
+package pushbullet
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// maxResponseSize limits the size of API response bodies.
+const maxResponseSize = 16 << 20
+
+// ErrResponseTooLarge is returned when an API response exceeds the size
+// limit.
+var ErrResponseTooLarge = errors.New("Response too large")
+
+// exec performs an API request and decodes the JSON response into v if v is
+// not nil. Every API call goes through exec, so error mapping and limits
+// apply to all endpoints alike. The query is added to the URL and body, if
+// not nil, is sent as JSON.
+func (c *Client) exec(method, path string, query url.Values, body, v interface{}) error {
+	req, err := c.buildRequest(method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, retryable(method, body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return err
+	}
+	if len(b) > maxResponseSize {
+		return ErrResponseTooLarge
+	}
+	if v == nil || len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// retryBackoff is the delay before the first retry of a failed request. It
+// doubles with every further retry.
+var retryBackoff = 500 * time.Millisecond
+
+// do sends req. If retry is set, requests that fail with a network error or
+// a server error are retried up to c.Retries times. Retries replay the same
+// body, so a push keeps its guid and is not created twice.
+func (c *Client) do(req *http.Request, retry bool) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := c.Client.Do(req)
+		if !retry || attempt >= c.Retries || (err == nil && resp.StatusCode < 500) {
+			return resp, err
+		}
+		if err == nil {
+			resp.Body.Close()
+		}
+		time.Sleep(retryBackoff << uint(attempt))
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// retryable reports whether a request can be sent again without side
+// effects: reads, deletes and pushes that carry a guid.
+func retryable(method string, body interface{}) bool {
+	switch method {
+	case "GET", "DELETE":
+		return true
+	}
+	g, ok := body.(guider)
+	return ok && g.guid() != ""
+}
+
+// get fetches path and returns the decoded response.
+func get[T any](c *Client, path string, query url.Values) (*T, error) {
+	var v T
+	if err := c.exec("GET", path, query, nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// post sends body to path and returns the decoded response.
+func post[T any](c *Client, path string, body interface{}) (*T, error) {
+	var v T
+	if err := c.exec("POST", path, nil, body, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
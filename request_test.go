@@ -0,0 +1,151 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedRequest struct {
+	Method string
+	URI    string
+	Body   string
+}
+
+func RequestResponseStub(reqs *[]recordedRequest, status int, resp string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		*reqs = append(*reqs, recordedRequest{r.Method, r.RequestURI, string(b)})
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Ratelimit-Reset", "1428364800")
+		w.WriteHeader(status)
+		w.Write([]byte(resp))
+	}))
+}
+
+func TestExecMethodsAndQuery(t *testing.T) {
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, `{"iden": "x"}`)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	v, err := get[Push](pb, "/pushes", url.Values{"q": {"a b&c"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "x", v.Iden)
+
+	_, err = post[Push](pb, "/pushes", n)
+	assert.NoError(t, err)
+
+	err = pb.exec("DELETE", "/pushes/x", nil, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "GET", reqs[0].Method)
+	assert.Equal(t, "/pushes?q=a+b%26c", reqs[0].URI)
+	assert.Equal(t, "POST", reqs[1].Method)
+	var note Note
+	json.Unmarshal([]byte(reqs[1].Body), &note)
+	assert.Equal(t, n, &note)
+	assert.Equal(t, "DELETE", reqs[2].Method)
+	assert.Equal(t, "", reqs[2].Body)
+}
+
+func TestExecEmptyResponse(t *testing.T) {
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, "")
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	v, err := get[Push](pb, "/pushes/x", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, &Push{}, v)
+}
+
+func TestExecResponseTooLarge(t *testing.T) {
+	var reqs []recordedRequest
+	big := `{"body": "` + strings.Repeat("x", maxResponseSize) + `"}`
+	server := RequestResponseStub(&reqs, http.StatusOK, big)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := get[Push](pb, "/pushes/x", nil)
+	assert.Equal(t, ErrResponseTooLarge, err)
+}
+
+func TestExecErrors(t *testing.T) {
+	var reqs []recordedRequest
+	errJSON, _ := json.Marshal(errorResponse{*e})
+	server := RequestResponseStub(&reqs, http.StatusInternalServerError, string(errJSON))
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	_, err := pb.Chats()
+	assert.Equal(t, e, err)
+	server.Close()
+
+	server = RequestResponseStub(&reqs, http.StatusTooManyRequests, "")
+	defer server.Close()
+	pb.Endpoint.URL = server.URL
+	err = pb.DeletePush("x")
+	assert.IsType(t, &RateLimitError{}, err)
+	assert.Equal(t, int64(1428364800), err.(*RateLimitError).Reset.Unix())
+}
+
+func TestExecBadJSON(t *testing.T) {
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, `{"devices": [`)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := pb.Devices()
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrResponseTooLarge, err)
+}
+
+func TestExecRetries(t *testing.T) {
+	var bodies []string
+	failures := 2
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, r.Method+" "+string(b))
+		if failures > 0 {
+			failures--
+			http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"iden": "push-iden"}`))
+	}))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	// A push is replayed with the same body, and thus the same guid.
+	push, err := pb.PushNote(d.Iden, n.Title, n.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, "push-iden", push.Iden)
+	assert.Len(t, bodies, 3)
+	assert.Contains(t, bodies[0], `"guid":"`)
+	assert.Equal(t, bodies[0], bodies[1])
+	assert.Equal(t, bodies[0], bodies[2])
+
+	// Other POSTs may have side effects and are sent once.
+	bodies, failures = nil, 1
+	err = pb.PushSMS("user", d.Iden, "+1 303 555 1212", "Hello!")
+	assert.Equal(t, "503 Service Unavailable", err.Error())
+	assert.Len(t, bodies, 1)
+
+	// Reads give up after Retries.
+	bodies, failures = nil, 5
+	pb.Retries = 1
+	_, err = pb.Chats()
+	assert.Error(t, err)
+	assert.Len(t, bodies, 2)
+}
@@ -0,0 +1,332 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrScheduleNotFound is returned when cancelling a scheduled push that does
+// not exist.
+var ErrScheduleNotFound = errors.New("Scheduled push not found")
+
+// ErrSchedulerClosed is returned when scheduling on a closed Scheduler.
+var ErrSchedulerClosed = errors.New("Scheduler closed")
+
+// A ScheduledPush is a push waiting in a Scheduler. Spec is the cron
+// specification of recurring pushes and empty for one-off pushes.
+type ScheduledPush struct {
+	ID       string          `json:"id"`
+	EndPoint string          `json:"endpoint"`
+	Data     json.RawMessage `json:"data"`
+	Next     time.Time       `json:"next"`
+	Spec     string          `json:"spec,omitempty"`
+}
+
+// SchedulerOptions configures a Scheduler.
+type SchedulerOptions struct {
+	// RetryInterval is how long to wait before sending a one-off push again
+	// after it failed with an error that may go away, such as a network or
+	// server error. Defaults to one minute.
+	RetryInterval time.Duration
+
+	// OnError, if set, is called when sending a scheduled push fails.
+	OnError func(p ScheduledPush, err error)
+}
+
+// A Scheduler sends pushes at a later time, once or repeatedly according to a
+// cron specification. Scheduled pushes are persisted to disk; pushes that
+// became due while the scheduler was not running are sent when it is opened
+// again. A one-off push to "/pushes" stays scheduled until it has been
+// delivered or PushBullet rejects it; its guid keeps retries from notifying
+// twice. Other endpoints, such as "/ephemerals" for SMS, have no guid and are
+// sent at most once. A recurring push that fails is not retried; it is sent
+// again at its next occurrence.
+type Scheduler struct {
+	Client *Client
+
+	opts SchedulerOptions
+	path string
+
+	mu      sync.Mutex
+	pushes  []ScheduledPush
+	notify  chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	closed  bool
+}
+
+// OpenScheduler opens the schedule persisted at path, creating it if it does
+// not exist, and starts sending due pushes with the client. opts may be nil.
+func OpenScheduler(c *Client, path string, opts *SchedulerOptions) (*Scheduler, error) {
+	var o SchedulerOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.RetryInterval == 0 {
+		o.RetryInterval = time.Minute
+	}
+
+	s := &Scheduler{
+		Client:  c,
+		opts:    o,
+		path:    path,
+		notify:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &s.pushes); err != nil {
+			return nil, err
+		}
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// At schedules data to be pushed to endPoint at time t, like Client.Push. It
+// returns the ID of the scheduled push.
+func (s *Scheduler) At(t time.Time, endPoint string, data interface{}) (string, error) {
+	return s.add(ScheduledPush{EndPoint: endPoint, Next: t}, data)
+}
+
+// After schedules data to be pushed to endPoint once d has elapsed.
+func (s *Scheduler) After(d time.Duration, endPoint string, data interface{}) (string, error) {
+	return s.At(time.Now().Add(d), endPoint, data)
+}
+
+// Every schedules data to be pushed to endPoint repeatedly according to the
+// cron specification spec, such as "0 8 * * mon" for every Monday at 08:00
+// local time. The five fields are minute, hour, day of month, month and day
+// of week; the macros @hourly, @daily, @weekly, @monthly and @yearly are
+// accepted as well.
+func (s *Scheduler) Every(spec, endPoint string, data interface{}) (string, error) {
+	cron, err := parseCron(spec)
+	if err != nil {
+		return "", err
+	}
+	next := cron.next(time.Now())
+	if next.IsZero() {
+		return "", errors.New("Cron spec " + spec + " never matches")
+	}
+	return s.add(ScheduledPush{EndPoint: endPoint, Next: next, Spec: spec}, data)
+}
+
+func (s *Scheduler) add(p ScheduledPush, data interface{}) (string, error) {
+	if err := s.Client.validate(data); err != nil {
+		return "", err
+	}
+	var b []byte
+	var err error
+	if p.EndPoint == "/pushes" && p.Spec == "" {
+		// The guid is fixed up front, so that sending the push again
+		// after a failure cannot notify twice.
+		b, _, err = objectWithGUID(data, "")
+	} else {
+		b, err = json.Marshal(data)
+	}
+	if err != nil {
+		return "", err
+	}
+	p.ID = newGUID()
+	p.Data = b
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return "", ErrSchedulerClosed
+	}
+	s.pushes = append(s.pushes, p)
+	if err := s.save(); err != nil {
+		s.pushes = s.pushes[:len(s.pushes)-1]
+		return "", err
+	}
+	s.wake()
+	return p.ID, nil
+}
+
+// List returns the scheduled pushes ordered by the time they are due.
+func (s *Scheduler) List() []ScheduledPush {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := append([]ScheduledPush(nil), s.pushes...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Next.Before(list[j].Next)
+	})
+	return list
+}
+
+// Cancel removes the scheduled push with the given ID.
+func (s *Scheduler) Cancel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, p := range s.pushes {
+		if p.ID == id {
+			s.pushes = append(s.pushes[:i:i], s.pushes[i+1:]...)
+			s.wake()
+			return s.save()
+		}
+	}
+	return ErrScheduleNotFound
+}
+
+// Close stops the scheduler. Scheduled pushes remain on disk.
+func (s *Scheduler) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.done)
+	<-s.stopped
+	return nil
+}
+
+func (s *Scheduler) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) run() {
+	defer close(s.stopped)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.notify:
+		case <-timer.C:
+		}
+
+		wait := s.sendDue(time.Now())
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+	}
+}
+
+// sendDue sends all pushes due at now and returns how long to wait for the
+// next one.
+func (s *Scheduler) sendDue(now time.Time) time.Duration {
+	s.mu.Lock()
+	var due []ScheduledPush
+	kept := s.pushes[:0]
+	for _, p := range s.pushes {
+		if !p.Next.After(now) {
+			due = append(due, p)
+			if p.Spec != "" {
+				// A recurring push moves on to its next occurrence
+				// before it is sent, so no occurrence is sent twice.
+				if cron, err := parseCron(p.Spec); err == nil {
+					p.Next = cron.next(now)
+				}
+				if p.Next.IsZero() || !p.Next.After(now) {
+					continue
+				}
+			}
+		}
+		kept = append(kept, p)
+	}
+	s.pushes = kept
+	var saveErr error
+	if len(due) > 0 {
+		saveErr = s.save()
+	}
+	s.mu.Unlock()
+
+	if saveErr != nil {
+		s.report(ScheduledPush{}, saveErr)
+	}
+	for _, p := range due {
+		_, err := s.Client.Push(p.EndPoint, p.payload())
+		if p.Spec == "" {
+			if saveErr := s.finish(p, err); saveErr != nil {
+				s.report(p, saveErr)
+			}
+		}
+		if err != nil {
+			s.report(p, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wait := time.Hour
+	for _, p := range s.pushes {
+		if d := p.Next.Sub(now); d < wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// finish removes the one-off push p once it has been sent, or reschedules it
+// after RetryInterval if sending it failed with an error that may go away.
+// Only pushes are retried: without a guid the server may have acted on the
+// failed request already.
+func (s *Scheduler) finish(p ScheduledPush, err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.pushes {
+		if s.pushes[i].ID != p.ID {
+			continue
+		}
+		if err != nil && !rejected(err) && p.EndPoint == "/pushes" {
+			s.pushes[i].Next = time.Now().Add(s.opts.RetryInterval)
+		} else {
+			s.pushes = append(s.pushes[:i:i], s.pushes[i+1:]...)
+		}
+		return s.save()
+	}
+	return nil
+}
+
+func (s *Scheduler) report(p ScheduledPush, err error) {
+	if s.opts.OnError != nil {
+		s.opts.OnError(p, err)
+	}
+}
+
+// payload returns the data to push for this occurrence of p. Pushes get a
+// guid; each occurrence of a recurring push gets a new one, as PushBullet
+// would otherwise take it for the previous occurrence. One-off pushes keep the
+// guid they were given when they were scheduled.
+func (p ScheduledPush) payload() interface{} {
+	if p.EndPoint != "/pushes" {
+		return p.Data
+	}
+	var guid string
+	if p.Spec != "" {
+		guid = newGUID()
+	}
+	b, _, err := objectWithGUID(p.Data, guid)
+	if err != nil {
+		return p.Data
+	}
+	return json.RawMessage(b)
+}
+
+// save writes the schedule to disk. s.mu must be held.
+func (s *Scheduler) save() error {
+	return writeJSONFile(s.path, s.pushes)
+}
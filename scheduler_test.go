@@ -0,0 +1,129 @@
+package pushbullet
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	server := OutboxResponseStub()
+	defer server.Close()
+	server.setOnline(true)
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	s, err := OpenScheduler(pb, path, nil)
+	assert.NoError(t, err)
+	later, err := s.After(time.Hour, "/pushes", n)
+	assert.NoError(t, err)
+	weekly, err := s.Every("0 8 * * mon", "/pushes", n)
+	assert.NoError(t, err)
+	_, err = s.Every("0 8 * *", "/pushes", n)
+	assert.Error(t, err)
+	_, err = s.At(time.Now(), "/pushes", map[string]string{"guid": "now"})
+	assert.NoError(t, err)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.List()) > 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	list := s.List()
+	if assert.Len(t, list, 2) {
+		assert.Equal(t, later, list[0].ID)
+		assert.Equal(t, weekly, list[1].ID)
+		assert.Equal(t, time.Monday, list[1].Next.Weekday())
+	}
+	assert.NoError(t, s.Cancel(later))
+	assert.Equal(t, ErrScheduleNotFound, s.Cancel(later))
+	assert.NoError(t, s.Close())
+	_, err = s.After(time.Hour, "/pushes", n)
+	assert.Equal(t, ErrSchedulerClosed, err)
+
+	server.mu.Lock()
+	assert.Equal(t, []string{"now"}, server.guids)
+	server.mu.Unlock()
+
+	s, err = OpenScheduler(pb, path, nil)
+	assert.NoError(t, err)
+	defer s.Close()
+	list = s.List()
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, weekly, list[0].ID)
+		assert.Equal(t, "0 8 * * mon", list[0].Spec)
+	}
+}
+
+func TestSchedulerRetry(t *testing.T) {
+	server := OutboxResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	failed := make(chan error, 10)
+	s, err := OpenScheduler(pb, path, &SchedulerOptions{
+		RetryInterval: 50 * time.Millisecond,
+		OnError:       func(p ScheduledPush, err error) { failed <- err },
+	})
+	assert.NoError(t, err)
+	defer s.Close()
+	id, err := s.At(time.Now(), "/pushes", n)
+	assert.NoError(t, err)
+
+	select {
+	case err := <-failed:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("push was not sent")
+	}
+	list := s.List()
+	if assert.Len(t, list, 1) {
+		assert.Equal(t, id, list[0].ID)
+	}
+
+	server.setOnline(true)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.List()) > 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Len(t, s.List(), 0)
+	server.mu.Lock()
+	if assert.Len(t, server.guids, 1) {
+		assert.NotEmpty(t, server.guids[0])
+	}
+	server.mu.Unlock()
+}
+
+func TestSchedulerNoRetryWithoutGUID(t *testing.T) {
+	server := OutboxResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	failed := make(chan ScheduledPush, 10)
+	sched, err := OpenScheduler(pb, path, &SchedulerOptions{
+		RetryInterval: 10 * time.Millisecond,
+		OnError:       func(p ScheduledPush, err error) { failed <- p },
+	})
+	assert.NoError(t, err)
+	defer sched.Close()
+	id, err := sched.At(time.Now(), "/ephemerals", s)
+	assert.NoError(t, err)
+
+	select {
+	case p := <-failed:
+		assert.Equal(t, id, p.ID)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ephemeral was not sent")
+	}
+	assert.Len(t, sched.List(), 0)
+}
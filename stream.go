@@ -0,0 +1,88 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/xconstruct/go-pushbullet/internal/websocket"
+)
+
+// streamTimeout is how long a Stream waits for a message before giving up.
+// PushBullet sends a nop message every 30 seconds.
+const streamTimeout = 90 * time.Second
+
+// A StreamMessage is a message received on the realtime event stream. Type
+// is "nop" for keep-alives, "tickle" when something changed on the server,
+// in which case Subtype tells what ("push" or "device"), or "push" for an
+// ephemeral, which is then in Push.
+type StreamMessage struct {
+	Type    string          `json:"type"`
+	Subtype string          `json:"subtype,omitempty"`
+	Push    json.RawMessage `json:"push,omitempty"`
+}
+
+// Ephemeral decodes the ephemeral of a message of type "push".
+func (m *StreamMessage) Ephemeral() (*StreamEphemeral, error) {
+	var e StreamEphemeral
+	if err := json.Unmarshal(m.Push, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// A StreamEphemeral is an ephemeral received on the stream, such as a
+// notification mirrored from a phone or a change of its SMS threads.
+type StreamEphemeral struct {
+	Type             string             `json:"type"`
+	SourceDeviceIden string             `json:"source_device_iden,omitempty"`
+	SourceUserIden   string             `json:"source_user_iden,omitempty"`
+	ApplicationName  string             `json:"application_name,omitempty"`
+	PackageName      string             `json:"package_name,omitempty"`
+	NotificationID   string             `json:"notification_id,omitempty"`
+	Title            string             `json:"title,omitempty"`
+	Body             string             `json:"body,omitempty"`
+	Notifications    []*SMSNotification `json:"notifications,omitempty"`
+}
+
+// An SMSNotification is an incoming text message reported by an
+// "sms_changed" ephemeral.
+type SMSNotification struct {
+	ThreadID  string    `json:"thread_id"`
+	Title     string    `json:"title"`
+	Body      string    `json:"body"`
+	Timestamp Timestamp `json:"timestamp"`
+}
+
+// A Stream is a connection to the realtime event stream.
+type Stream struct {
+	conn *websocket.Conn
+}
+
+// Stream connects to the realtime event stream of the user.
+func (c *Client) Stream() (*Stream, error) {
+	conn, err := websocket.Dial(c.Endpoint.StreamURL+c.Key, 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{conn}, nil
+}
+
+// Next waits for the next message. An error means the connection is lost
+// and the caller should reconnect.
+func (s *Stream) Next() (*StreamMessage, error) {
+	s.conn.SetReadDeadline(time.Now().Add(streamTimeout))
+	b, err := s.conn.ReadMessage()
+	if err != nil {
+		return nil, err
+	}
+	var m StreamMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// Close closes the connection.
+func (s *Stream) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,72 @@
+package pushbullet
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func StreamResponseStub(t *testing.T, messages ...string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/websocket/"+k, r.URL.Path)
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		h := sha1.Sum([]byte(r.Header.Get("Sec-Websocket-Key") + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+		rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+		for _, m := range messages {
+			if len(m) < 126 {
+				rw.Write([]byte{0x81, byte(len(m))})
+			} else {
+				rw.Write([]byte{0x81, 126, byte(len(m) >> 8), byte(len(m))})
+			}
+			rw.WriteString(m)
+		}
+		rw.Write([]byte{0x88, 0})
+		rw.Flush()
+		rw.ReadByte()
+	}))
+}
+
+func TestStream(t *testing.T) {
+	server := StreamResponseStub(t,
+		`{"type": "nop"}`,
+		`{"type": "tickle", "subtype": "push"}`,
+		`{"type": "push", "push": {"type": "sms_changed", "source_device_iden": "ujpah72o0sjAoRtnM0jc", "notifications": [{"thread_id": "1", "title": "Mom", "body": "Call me", "timestamp": 1412047948}]}}`,
+	)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.StreamURL = strings.Replace(server.URL, "http", "ws", 1) + "/websocket/"
+
+	s, err := pb.Stream()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer s.Close()
+
+	m, err := s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, "nop", m.Type)
+	m, err = s.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, &StreamMessage{Type: "tickle", Subtype: "push"}, m)
+	m, err = s.Next()
+	assert.NoError(t, err)
+	eph, err := m.Ephemeral()
+	assert.NoError(t, err)
+	assert.Equal(t, "sms_changed", eph.Type)
+	if assert.Len(t, eph.Notifications, 1) {
+		assert.Equal(t, &SMSNotification{ThreadID: "1", Title: "Mom", Body: "Call me", Timestamp: ts("1412047948")}, eph.Notifications[0])
+	}
+	_, err = s.Next()
+	assert.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package pushbullet
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrThrottleClosed is returned when pushing through a closed Throttle.
+var ErrThrottleClosed = errors.New("Throttle closed")
+
+// A Throttle suppresses repeated pushes. The first push with a given target,
+// title and body is sent right away; identical pushes within the following
+// window are dropped. When the window ends and pushes were dropped, a single
+// digest note reporting how many were suppressed is sent in their place and
+// a new window starts.
+type Throttle struct {
+	Client *Client
+	Window time.Duration
+
+	// OnSuppress, if set, is called for every push that is dropped.
+	OnSuppress func(t Target, title, body string)
+
+	// OnError, if set, is called when sending a digest fails.
+	OnError func(t Target, err error)
+
+	mu      sync.Mutex
+	windows map[string]*throttleWindow
+	stats   ThrottleStats
+	closed  bool
+}
+
+// ThrottleStats counts what a Throttle did with the pushes it was given.
+type ThrottleStats struct {
+	Sent       int // pushes sent
+	Suppressed int // pushes dropped
+	Digests    int // digest notes sent
+}
+
+type throttleWindow struct {
+	target     Target
+	title      string
+	suppressed int
+	timer      *time.Timer
+}
+
+// NewThrottle creates a Throttle that suppresses identical pushes within
+// window.
+func NewThrottle(c *Client, window time.Duration) *Throttle {
+	return &Throttle{
+		Client:  c,
+		Window:  window,
+		windows: make(map[string]*throttleWindow),
+	}
+}
+
+// PushNote pushes a note with title and body to the target unless an
+// identical note was pushed within the window. It reports whether the note
+// was sent.
+func (t *Throttle) PushNote(target Target, title, body string) (bool, error) {
+	if ok, err := t.allow(target, title, "", body); !ok {
+		return false, err
+	}
+	_, err := t.Client.Push("/pushes", target.note(title, body))
+	return true, err
+}
+
+// PushLink pushes a link with title, url and body to the target unless an
+// identical link was pushed within the window. It reports whether the link
+// was sent.
+func (t *Throttle) PushLink(target Target, title, u, body string) (bool, error) {
+	if ok, err := t.allow(target, title, u, body); !ok {
+		return false, err
+	}
+	_, err := t.Client.Push("/pushes", target.link(title, u, body))
+	return true, err
+}
+
+// Stats returns the number of pushes sent and suppressed so far.
+func (t *Throttle) Stats() ThrottleStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// Close ends all open windows, sending the digests of those that suppressed
+// pushes. Pushing through a closed Throttle fails with ErrThrottleClosed.
+func (t *Throttle) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	var digests []*throttleWindow
+	for key, w := range t.windows {
+		w.timer.Stop()
+		delete(t.windows, key)
+		if w.suppressed > 0 {
+			digests = append(digests, w)
+		}
+	}
+	t.mu.Unlock()
+
+	var err error
+	for _, w := range digests {
+		if e := t.sendDigest(w.target, w.title, w.suppressed); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// allow records a push and reports whether it should be sent. Pushes are
+// identical if they have the same target, title, url and body; url is empty
+// for notes.
+func (t *Throttle) allow(target Target, title, url, body string) (bool, error) {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%x", target, title, url, sha256.Sum256([]byte(body)))
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return false, ErrThrottleClosed
+	}
+	if w, ok := t.windows[key]; ok {
+		w.suppressed++
+		t.stats.Suppressed++
+		t.mu.Unlock()
+		if t.OnSuppress != nil {
+			t.OnSuppress(target, title, body)
+		}
+		return false, nil
+	}
+
+	w := &throttleWindow{target: target, title: title}
+	w.timer = time.AfterFunc(t.Window, func() { t.endWindow(key, w) })
+	t.windows[key] = w
+	t.stats.Sent++
+	t.mu.Unlock()
+	return true, nil
+}
+
+// endWindow sends the digest of a window that suppressed pushes and starts a
+// new window, or forgets the window if nothing was suppressed.
+func (t *Throttle) endWindow(key string, w *throttleWindow) {
+	t.mu.Lock()
+	if t.windows[key] != w {
+		t.mu.Unlock()
+		return
+	}
+	n := w.suppressed
+	if n == 0 {
+		delete(t.windows, key)
+		t.mu.Unlock()
+		return
+	}
+	w.suppressed = 0
+	w.timer.Reset(t.Window)
+	t.mu.Unlock()
+
+	if err := t.sendDigest(w.target, w.title, n); err != nil && t.OnError != nil {
+		t.OnError(w.target, err)
+	}
+}
+
+func (t *Throttle) sendDigest(target Target, title string, n int) error {
+	t.mu.Lock()
+	t.stats.Digests++
+	t.mu.Unlock()
+
+	alerts := "alerts"
+	if n == 1 {
+		alerts = "alert"
+	}
+	body := fmt.Sprintf("%d more %s suppressed in the last %s", n, alerts, t.Window)
+	_, err := t.Client.Push("/pushes", target.note(title, body))
+	return err
+}
@@ -0,0 +1,116 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottle(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var note Note
+		json.NewDecoder(r.Body).Decode(&note)
+		mu.Lock()
+		bodies = append(bodies, note.Body)
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	th := NewThrottle(pb, time.Hour)
+	var dropped int
+	th.OnSuppress = func(Target, string, string) { dropped++ }
+	target := DeviceTarget(d.Iden)
+
+	for i := 0; i < 13; i++ {
+		sent, err := th.PushNote(target, "disk full", "/var is at 100%")
+		assert.NoError(t, err)
+		assert.Equal(t, i == 0, sent)
+	}
+	sent, err := th.PushNote(target, "disk full", "/home is at 100%")
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	sent, err = th.PushNote(ChannelTarget("ops"), "disk full", "/var is at 100%")
+	assert.NoError(t, err)
+	assert.True(t, sent)
+
+	assert.Equal(t, 12, dropped)
+	assert.NoError(t, th.Close())
+	assert.Equal(t, ThrottleStats{Sent: 3, Suppressed: 12, Digests: 1}, th.Stats())
+	assert.Contains(t, bodies, "12 more alerts suppressed in the last 1h0m0s")
+	assert.Len(t, bodies, 4)
+}
+
+func TestThrottleWindowExpires(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var note Note
+		json.NewDecoder(r.Body).Decode(&note)
+		mu.Lock()
+		bodies = append(bodies, note.Body)
+		mu.Unlock()
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	th := NewThrottle(pb, 20*time.Millisecond)
+	defer th.Close()
+	target := DeviceTarget(d.Iden)
+	th.PushNote(target, n.Title, n.Body)
+	th.PushNote(target, n.Title, n.Body)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for th.Stats().Digests == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(60 * time.Millisecond)
+	sent, _ := th.PushNote(target, n.Title, n.Body)
+	assert.True(t, sent)
+	mu.Lock()
+	assert.Equal(t, []string{n.Body, "1 more alert suppressed in the last 20ms", n.Body}, bodies)
+	mu.Unlock()
+}
+
+func TestThrottleLink(t *testing.T) {
+	var reqs []recordedRequest
+	server := RequestResponseStub(&reqs, http.StatusOK, `{}`)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	th := NewThrottle(pb, time.Hour)
+	var suppressed []string
+	th.OnSuppress = func(_ Target, title, body string) { suppressed = append(suppressed, body) }
+	target := DeviceTarget(d.Iden)
+
+	sent, err := th.PushLink(target, l.Title, l.URL, l.Body)
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	sent, err = th.PushLink(target, l.Title, l.URL, l.Body)
+	assert.NoError(t, err)
+	assert.False(t, sent)
+	sent, err = th.PushLink(target, l.Title, "https://www.google.com/maps", l.Body)
+	assert.NoError(t, err)
+	assert.True(t, sent)
+	assert.Equal(t, []string{l.Body}, suppressed)
+
+	assert.NoError(t, th.Close())
+	sent, err = th.PushLink(target, l.Title, l.URL, l.Body)
+	assert.Equal(t, ErrThrottleClosed, err)
+	assert.False(t, sent)
+	_, err = th.PushNote(target, n.Title, n.Body)
+	assert.Equal(t, ErrThrottleClosed, err)
+	assert.Equal(t, ThrottleStats{Sent: 2, Suppressed: 1, Digests: 1}, th.Stats())
+}
@@ -0,0 +1,93 @@
+package pushbullet
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Timestamp is a point in time as sent by PushBullet, in fractional seconds
+// since the epoch. It is decoded from the JSON text directly, so no precision
+// is lost to floating point. Zero seconds decode to the zero time.
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" {
+		return nil
+	}
+	tm, err := parseEpoch(s)
+	if err != nil {
+		return errors.New("Invalid timestamp " + s)
+	}
+	t.Time = tm
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return []byte(formatEpoch(t.Time)), nil
+}
+
+// parseEpoch parses fractional epoch seconds with nanosecond precision.
+func parseEpoch(s string) (time.Time, error) {
+	var sec, nsec int64
+	if strings.ContainsAny(s, "eE-") {
+		f, _, err := big.ParseFloat(s, 10, 128, big.ToNearestEven)
+		if err != nil || f.IsInf() {
+			return time.Time{}, errors.New("invalid")
+		}
+		sec, _ = f.Int64()
+		frac := new(big.Float).Sub(f, new(big.Float).SetInt64(sec))
+		ns, _ := frac.Mul(frac, big.NewFloat(1e9)).Float64()
+		nsec = int64(math.Round(ns))
+	} else {
+		whole, frac := s, ""
+		if i := strings.IndexByte(s, '.'); i >= 0 {
+			whole, frac = s[:i], s[i+1:]
+		}
+		var err error
+		if sec, err = strconv.ParseInt(whole, 10, 64); err != nil {
+			return time.Time{}, err
+		}
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		if frac != "" {
+			frac += strings.Repeat("0", 9-len(frac))
+			if nsec, err = strconv.ParseInt(frac, 10, 64); err != nil {
+				return time.Time{}, err
+			}
+		}
+	}
+	if sec == 0 && nsec == 0 {
+		return time.Time{}, nil
+	}
+	return time.Unix(sec, nsec), nil
+}
+
+// formatEpoch formats t as fractional epoch seconds, the zero time as 0.
+func formatEpoch(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	sec, ns := t.Unix(), t.Nanosecond()
+	neg := sec < 0
+	if neg && ns != 0 {
+		sec, ns = sec+1, 1e9-ns
+	}
+	s := strconv.FormatInt(sec, 10)
+	if neg && sec == 0 {
+		s = "-0"
+	}
+	if ns != 0 {
+		s += "." + strings.TrimRight(strconv.Itoa(1e9 + ns)[1:], "0")
+	}
+	return s
+}
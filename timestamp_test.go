@@ -0,0 +1,63 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ts parses fractional epoch seconds for test fixtures.
+func ts(s string) Timestamp {
+	t, err := parseEpoch(s)
+	if err != nil {
+		panic(err)
+	}
+	return Timestamp{t}
+}
+
+func TestTimestampUnmarshal(t *testing.T) {
+	for in, want := range map[string]time.Time{
+		`1412047948.579029`:     time.Unix(1412047948, 579029000),
+		`1412047948.5790291234`: time.Unix(1412047948, 579029123),
+		`1412047948`:            time.Unix(1412047948, 0),
+		`1.412047948579029e+09`: time.Unix(1412047948, 579029000),
+		`1.4120479485e9`:        time.Unix(1412047948, 500000000),
+		`-1.5`:                  time.Unix(-2, 500000000),
+		`0`:                     {},
+		`0.0`:                   {},
+		`null`:                  {},
+	} {
+		var ts Timestamp
+		err := json.Unmarshal([]byte(in), &ts)
+		assert.NoError(t, err, in)
+		assert.True(t, want.Equal(ts.Time), "%s: got %v, want %v", in, ts.Time, want)
+	}
+
+	var ts Timestamp
+	assert.Error(t, json.Unmarshal([]byte(`"yesterday"`), &ts))
+	assert.Error(t, json.Unmarshal([]byte(`1.5x`), &ts))
+}
+
+func TestTimestampMarshal(t *testing.T) {
+	for want, in := range map[string]time.Time{
+		`1412047948.579029`: time.Unix(1412047948, 579029000),
+		`1412047948`:        time.Unix(1412047948, 0),
+		`-1.5`:              time.Unix(-2, 500000000),
+		`0`:                 {},
+	} {
+		b, err := json.Marshal(Timestamp{in})
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(b))
+	}
+}
+
+func TestTimestampRoundTrip(t *testing.T) {
+	var dev Device
+	b, _ := json.Marshal(d)
+	assert.NoError(t, json.Unmarshal(b, &dev))
+	assert.Equal(t, int64(1412047948), dev.Created.Unix())
+	assert.Equal(t, 579029000, dev.Created.Nanosecond())
+	assert.True(t, d.Modified.Equal(dev.Modified.Time))
+}
@@ -0,0 +1,101 @@
+package pushbullet
+
+import (
+	"encoding/json"
+)
+
+// Preferences are the user's settings as stored by PushBullet. Apps keep
+// their own settings here too; those are preserved in Other, so that
+// updating the preferences does not lose them.
+type Preferences struct {
+	Onboarding *Onboarding `json:"onboarding,omitempty"`
+	Social     bool        `json:"social"`
+
+	// Other holds the preferences that have no field above, by key.
+	Other map[string]json.RawMessage `json:"-"`
+}
+
+// Onboarding records which introductions the user has completed.
+type Onboarding struct {
+	App       bool `json:"app"`
+	Friends   bool `json:"friends"`
+	Extension bool `json:"extension"`
+}
+
+// preferences has the fields of Preferences without its methods.
+type preferences Preferences
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *Preferences) UnmarshalJSON(b []byte) error {
+	var known preferences
+	if err := json.Unmarshal(b, &known); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	delete(all, "onboarding")
+	delete(all, "social")
+	if len(all) == 0 {
+		all = nil
+	}
+	*p = Preferences(known)
+	p.Other = all
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (p Preferences) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(preferences(p))
+	if err != nil || len(p.Other) == 0 {
+		return b, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for k, v := range p.Other {
+		if _, ok := all[k]; !ok {
+			all[k] = v
+		}
+	}
+	return json.Marshal(all)
+}
+
+// UpdatePreferences replaces the user's preferences and returns the updated
+// user. Start from the Preferences returned by Me to keep the settings of
+// other apps.
+func (c *Client) UpdatePreferences(p Preferences) (*User, error) {
+	data := struct {
+		Preferences Preferences `json:"preferences"`
+	}{p}
+	user, err := post[User](c, "/users/me", data)
+	if err != nil {
+		return nil, err
+	}
+	c.setMaxUploadSize(user.MaxUploadSize)
+	return user, nil
+}
+
+// MaxUploadSize returns the largest file in bytes the user may upload. It is
+// fetched with Me on first use.
+func (c *Client) MaxUploadSize() (int64, error) {
+	c.mu.Lock()
+	size := c.maxUploadSize
+	c.mu.Unlock()
+	if size > 0 {
+		return size, nil
+	}
+	user, err := c.Me()
+	if err != nil {
+		return 0, err
+	}
+	return user.MaxUploadSize, nil
+}
+
+func (c *Client) setMaxUploadSize(size int64) {
+	c.mu.Lock()
+	c.maxUploadSize = size
+	c.mu.Unlock()
+}
@@ -0,0 +1,108 @@
+package pushbullet
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func UserResponseStub(requests *int, posted *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/users/me" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		*requests++
+		if r.Method == "POST" {
+			b, _ := ioutil.ReadAll(r.Body)
+			*posted = string(b)
+		}
+		b, _ := json.Marshal(m)
+		w.Write(b)
+	}))
+}
+
+func TestPreferencesUnmarshal(t *testing.T) {
+	var u User
+	err := json.Unmarshal([]byte(`{
+		"iden": "ujpah72o0",
+		"max_upload_size": 26214400,
+		"pro": true,
+		"preferences": {
+			"onboarding": {"app": false, "friends": true, "extension": false},
+			"social": true,
+			"cat": {"name": "Tom"}
+		}
+	}`), &u)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(26214400), u.MaxUploadSize)
+	assert.True(t, u.Pro)
+	assert.Equal(t, &Onboarding{Friends: true}, u.Preferences.Onboarding)
+	assert.True(t, u.Preferences.Social)
+	assert.Equal(t, map[string]json.RawMessage{"cat": json.RawMessage(`{"name": "Tom"}`)}, u.Preferences.Other)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"preferences": []}`), &u))
+}
+
+func TestPreferencesMarshal(t *testing.T) {
+	b, err := json.Marshal(Preferences{Social: true})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"social": true}`, string(b))
+
+	b, err = json.Marshal(m.Preferences)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"onboarding": {"app": true, "friends": false, "extension": true},
+		"social": true,
+		"theme": "dark"
+	}`, string(b))
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	var requests int
+	var posted string
+	server := UserResponseStub(&requests, &posted)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	prefs := m.Preferences
+	prefs.Social = false
+	user, err := pb.UpdatePreferences(prefs)
+	assert.NoError(t, err)
+	assert.Equal(t, m, user)
+	assert.JSONEq(t, `{"preferences": {
+		"onboarding": {"app": true, "friends": false, "extension": true},
+		"social": false,
+		"theme": "dark"
+	}}`, posted)
+}
+
+func TestMaxUploadSize(t *testing.T) {
+	var requests int
+	var posted string
+	server := UserResponseStub(&requests, &posted)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	for i := 0; i < 2; i++ {
+		size, err := pb.MaxUploadSize()
+		assert.NoError(t, err)
+		assert.Equal(t, int64(26214400), size)
+	}
+	assert.Equal(t, 1, requests)
+}
+
+func TestMaxUploadSizeError(t *testing.T) {
+	server := PushbulletErrJSONResponseStub()
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+	_, err := pb.MaxUploadSize()
+	assert.Equal(t, e, err)
+}
@@ -0,0 +1,200 @@
+package pushbullet
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNoTargets is returned by a fan-out push given no targets.
+var ErrNoTargets = errors.New("No targets")
+
+// A Validator is a push payload that can check itself before it is sent.
+// Client.Push validates payloads implementing it unless the client's
+// SkipValidation is set.
+type Validator interface {
+	Validate() error
+}
+
+// A FieldError describes a field of a push payload that is invalid.
+type FieldError struct {
+	Field   string // name of the field in the JSON payload
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationError is returned for a push payload that fails validation. It
+// is returned before anything is sent.
+type ValidationError struct {
+	Type   string // the push type
+	Fields []*FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Error()
+	}
+	return fmt.Sprintf("Invalid %s push: %s", e.Type, strings.Join(msgs, "; "))
+}
+
+// validation collects the field errors of a payload.
+type validation struct {
+	typ    string
+	fields []*FieldError
+}
+
+func (v *validation) add(field, format string, args ...interface{}) {
+	v.fields = append(v.fields, &FieldError{field, fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{v.typ, v.fields}
+}
+
+func (v *validation) kind(typ string) {
+	if typ != v.typ {
+		v.add("type", "must be %q, not %q", v.typ, typ)
+	}
+}
+
+// target checks that at most one target is given and that it is usable.
+func (v *validation) target(iden, tag, email string) {
+	var set []string
+	for _, f := range []struct{ name, value string }{
+		{"device_iden", iden},
+		{"channel_tag", tag},
+		{"email", email},
+	} {
+		if f.value == "" {
+			continue
+		}
+		set = append(set, f.name)
+		if strings.TrimSpace(f.value) != f.value {
+			v.add(f.name, "has surrounding whitespace")
+		}
+	}
+	if len(set) > 1 {
+		v.add(strings.Join(set, ", "), "are mutually exclusive")
+	}
+	if email != "" {
+		if _, err := mail.ParseAddress(email); err != nil {
+			v.add("email", "is not an email address")
+		}
+	}
+}
+
+func (v *validation) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		v.add(field, "is required")
+	}
+}
+
+func (v *validation) title(field, value string) {
+	if n := utf8.RuneCountInString(value); n > MaxTitleLength {
+		v.add(field, "is %d characters long, the limit is %d", n, MaxTitleLength)
+	}
+}
+
+func (v *validation) url(field, value string) {
+	if value == "" {
+		v.add(field, "is required")
+		return
+	}
+	if u, err := url.Parse(value); err != nil || !u.IsAbs() {
+		v.add(field, "is not an absolute URL")
+	}
+}
+
+// Validate checks the note before it is pushed.
+func (n Note) Validate() error {
+	v := validation{typ: "note"}
+	v.kind(n.Type)
+	v.target(n.Iden, n.Tag, n.Email)
+	if n.Title == "" && n.Body == "" {
+		v.add("title", "or body is required")
+	}
+	v.title("title", n.Title)
+	return v.err()
+}
+
+// Validate checks the link before it is pushed.
+func (l Link) Validate() error {
+	v := validation{typ: "link"}
+	v.kind(l.Type)
+	v.target(l.Iden, l.Tag, l.Email)
+	v.url("url", l.URL)
+	v.title("title", l.Title)
+	return v.err()
+}
+
+// Validate checks the file push before it is pushed.
+func (f File) Validate() error {
+	v := validation{typ: "file"}
+	v.kind(f.Type)
+	v.target(f.Iden, f.Tag, f.Email)
+	v.required("file_name", f.FileName)
+	v.required("file_type", f.FileType)
+	v.url("file_url", f.FileURL)
+	return v.err()
+}
+
+// Validate checks the address before it is pushed.
+func (a Address) Validate() error {
+	v := validation{typ: "address"}
+	v.kind(a.Type)
+	v.target(a.Iden, a.Tag, a.Email)
+	v.required("address", a.Address)
+	v.title("name", a.Name)
+	return v.err()
+}
+
+// Validate checks the checklist before it is pushed.
+func (l List) Validate() error {
+	v := validation{typ: "list"}
+	v.kind(l.Type)
+	v.target(l.Iden, l.Tag, l.Email)
+	v.title("title", l.Title)
+	for i, it := range l.Items {
+		v.required(fmt.Sprintf("items[%d].text", i), it.Text)
+	}
+	return v.err()
+}
+
+// Validate checks the ephemeral before it is pushed.
+func (e Ephemeral) Validate() error {
+	v := validation{typ: "ephemeral"}
+	if e.Type != "push" {
+		v.add("type", "must be %q, not %q", "push", e.Type)
+	}
+	p := e.Push
+	v.required("push.type", p.Type)
+	if p.Type == "messaging_extension_reply" {
+		v.required("push.package_name", p.PackageName)
+		v.required("push.source_user_iden", p.SourceUserIden)
+		v.required("push.target_device_iden", p.TargetDeviceIden)
+		v.required("push.conversation_iden", p.ConversationIden)
+		v.required("push.message", p.Message)
+	}
+	return v.err()
+}
+
+// validate validates data if it is a Validator and validation is enabled.
+func (c *Client) validate(data interface{}) error {
+	if c.SkipValidation {
+		return nil
+	}
+	if v, ok := data.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package pushbullet
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	long := strings.Repeat("é", MaxTitleLength+1)
+	for _, tc := range []struct {
+		data   Validator
+		fields []string
+	}{
+		{*n, nil},
+		{*l, nil},
+		{DeviceTarget("dev").note("", "body only"), nil},
+		{Target{}.note("", ""), []string{"title"}},
+		{Note{Type: "link", Title: "x"}, []string{"type"}},
+		{Note{Type: "note", Title: long}, []string{"title"}},
+		{Note{Type: "note", Title: "x", Iden: "dev", Tag: "ops"}, []string{"device_iden, channel_tag"}},
+		{Note{Type: "note", Title: "x", Tag: " ops"}, []string{"channel_tag"}},
+		{EmailTarget("elon").note("x", ""), []string{"email"}},
+		{EmailTarget("elon@teslamotors.com").note("x", ""), nil},
+		{Target{}.link("Google", "", ""), []string{"url"}},
+		{Target{}.link("Google", "www.google.com", ""), []string{"url"}},
+		{Target{}.file("a.txt", "text/plain", "https://files/a.txt", ""), nil},
+		{Target{}.file("", "", "", ""), []string{"file_name", "file_type", "file_url"}},
+		{Target{}.address("", " "), []string{"address"}},
+		{Target{}.list("Groceries", []ListItem{{Text: "Milk"}, {}}), []string{"items[1].text"}},
+		{Ephemeral{Type: "push", Push: *s}, nil},
+		{Ephemeral{Type: "push", Push: EphemeralPush{Type: "messaging_extension_reply", PackageName: "com.pushbullet.android"}},
+			[]string{"push.source_user_iden", "push.target_device_iden", "push.conversation_iden", "push.message"}},
+	} {
+		err := tc.data.Validate()
+		if tc.fields == nil {
+			assert.NoError(t, err, "%+v", tc.data)
+			continue
+		}
+		if assert.IsType(t, &ValidationError{}, err, "%+v", tc.data) {
+			var fields []string
+			for _, f := range err.(*ValidationError).Fields {
+				fields = append(fields, f.Field)
+			}
+			assert.Equal(t, tc.fields, fields)
+		}
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	err := Target{}.link("", "", "").Validate()
+	assert.Equal(t, "Invalid link push: url is required", err.Error())
+
+	err = Target{Device: "dev", Email: "x"}.note("", "").Validate()
+	assert.Equal(t, "Invalid note push: device_iden, email are mutually exclusive; email is not an email address; title or body is required", err.Error())
+}
+
+func TestPushValidation(t *testing.T) {
+	var requests int
+	var posted string
+	server := UserResponseStub(&requests, &posted)
+	defer server.Close()
+	pb := New(k)
+	pb.Endpoint.URL = server.URL
+
+	_, err := pb.PushLink(d.Iden, "Google", "", "")
+	assert.IsType(t, &ValidationError{}, err)
+	err = pb.PushSMS("", d.Iden, "+1 303 555 1212", "Hello!")
+	assert.IsType(t, &ValidationError{}, err)
+	_, err = pb.PushNoteMulti(nil, "title", "body")
+	assert.Equal(t, ErrNoTargets, err)
+	assert.Equal(t, 0, requests)
+
+	// Without validation, the payload reaches the server and is rejected
+	// there.
+	pb.SkipValidation = true
+	_, err = pb.PushLink(d.Iden, "Google", "", "")
+	assert.Equal(t, "404 Not Found", err.Error())
+}
+
+func TestQueuedPushValidation(t *testing.T) {
+	pb := New(k)
+	path := tempOutboxPath(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	o, err := OpenOutbox(pb, path, nil)
+	assert.NoError(t, err)
+	defer o.Close()
+	_, err = o.PushLink(d.Iden, "Google", "", "")
+	assert.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, 0, o.Len())
+
+	sched, err := OpenScheduler(pb, path+".schedule", nil)
+	assert.NoError(t, err)
+	defer sched.Close()
+	_, err = sched.After(time.Hour, "/pushes", Target{}.note("", ""))
+	assert.IsType(t, &ValidationError{}, err)
+	assert.Empty(t, sched.List())
+}